@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// BranchConfig is a snapshot of the branch.* git config, read with a single
+// git command instead of one git config --get per branch and variable.
+type BranchConfig map[string]string
+
+func LoadBranchConfig(ctx context.Context, connection shared.Connection) BranchConfig {
+	// git config exits with 1 when no key matches.
+	config, _ := connection.GetBranchConfig(ctx)
+	return ToBranchConfig(ToConfigEntries(SplitLines(config)))
+}
+
+func ToBranchConfig(entries []ConfigEntry) BranchConfig {
+	results := BranchConfig{}
+	for _, entry := range entries {
+		// As git config --get does, the last value wins.
+		results[entry.Key] = entry.Value
+	}
+	return results
+}
+
+// PullRequestNumbers returns the pull request numbers that the branches check out,
+// read from branch.<branchName>.merge, by branch name.
+func (c BranchConfig) PullRequestNumbers() map[string]int {
+	results := map[string]int{}
+	for key, value := range c {
+		if !strings.HasPrefix(key, "branch.") || !strings.HasSuffix(key, ".merge") {
+			continue
+		}
+		if n := getPRNumber(value); n > 0 {
+			results[strings.TrimSuffix(strings.TrimPrefix(key, "branch."), ".merge")] = n
+		}
+	}
+	return results
+}
+
+// Get returns the value of branch.<branchName>.<variable>, or an empty string when it is not set.
+func (c BranchConfig) Get(branchName string, variable string) string {
+	// git config prints variable names in lower case, but keeps branch names as is.
+	return c[fmt.Sprintf("branch.%s.%s", branchName, strings.ToLower(variable))]
+}
@@ -0,0 +1,28 @@
+package explain
+
+import (
+	"context"
+
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// ExplainBranch runs the same analysis as the main command with the options
+// for the branch only, without switching or deleting branches, and returns the
+// branch with its decision trace.
+func ExplainBranch(ctx context.Context, remote cmd.Remote, branchName string, connection shared.Connection, options cmd.Options) (shared.Branch, error) {
+	options.DryRun = true
+	options.BranchNames = []string{branchName}
+	branches, err := cmd.GetBranches(ctx, remote, connection, options)
+	if err != nil {
+		return shared.Branch{}, err
+	}
+
+	for _, branch := range branches {
+		if branch.Name == branchName {
+			return branch, nil
+		}
+	}
+
+	return shared.Branch{}, cmd.ErrNotFound
+}
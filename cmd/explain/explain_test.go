@@ -0,0 +1,109 @@
+package explain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/conn"
+	"github.com/seachicken/gh-poi/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrCommand = errors.New("failed to run external command")
+
+func Test_ExplainBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, conn.NewConf(&conn.Times{N: 1})).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "issue1", Filename: "issue1"},
+		}, nil, conn.NewConf(&conn.Times{N: 1})).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequests("issue1Merged", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+		}, nil, nil)
+	remote, _ := cmd.GetRemote(context.Background(), s.Conn)
+
+	actual, _ := ExplainBranch(context.Background(), remote, "issue1", s.Conn, cmd.Options{})
+
+	assert.Equal(t, "issue1", actual.Name)
+	assert.Equal(t, shared.Deletable, actual.State)
+	assert.Equal(t, []string{
+		"not listed by git branch --merged",
+		"no remote head: origin/issue1 is not fetched and git ls-remote git@github.com:owner/repo.git issue1 found nothing",
+		"kept 1 commit [a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0]: " +
+			"stopped at 6ebe3d30d23531af56bd23b5a098d3ccae2a534a, which is contained in main",
+		"searched pull requests by hash:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0, found #1 (issue1, merged)",
+		"matched #1 by the head branch name \"issue1\"",
+		"#1 is merged and contains the local head a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+		"deletable because a pull request is fully merged",
+	}, actual.Trace)
+}
+
+func Test_ExplainBranchDoesNotAnalyzeOtherBranches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, conn.NewConf(&conn.Times{N: 0})).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+		}, nil, nil)
+	remote, _ := cmd.GetRemote(context.Background(), s.Conn)
+
+	actual, _ := ExplainBranch(context.Background(), remote, "main", s.Conn, cmd.Options{})
+
+	assert.Equal(t, "main", actual.Name)
+	assert.Equal(t, shared.DefaultBranch, actual.Reason)
+}
+
+func Test_ExplainBranchReturnsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{}, nil, nil)
+	remote, _ := cmd.GetRemote(context.Background(), s.Conn)
+
+	_, err := ExplainBranch(context.Background(), remote, "issue2", s.Conn, cmd.Options{})
+
+	assert.Equal(t, cmd.ErrNotFound, err)
+}
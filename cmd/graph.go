@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// CommitGraph tells which branches contain a commit, like git branch --all --contains,
+// without running git for each commit.
+type CommitGraph struct {
+	branchNames []string
+	// containedIn holds a bit for each branch that contains the commit.
+	containedIn map[string][]uint64
+}
+
+// LoadCommitGraph reads the commits that the logs of the branches can reach with a single git rev-list.
+// The commits older than the common ancestor of the logs are not read, because no log reaches them.
+func LoadCommitGraph(ctx context.Context, branches []shared.Branch, logs [][]string, connection shared.Connection) (*CommitGraph, error) {
+	refs, err := connection.GetBranchRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branchNames, tipOids := toBranchRefs(SplitLines(refs))
+
+	// git branch --all lists the detached HEAD first.
+	headOids := []string{}
+	for _, branch := range branches {
+		if branch.IsDetached() {
+			branchNames = append([]string{branch.Name}, branchNames...)
+			tipOids = append([]string{branch.Oid}, tipOids...)
+			headOids = append(headOids, branch.Oid)
+		}
+	}
+
+	oldestOids := []string{}
+	for _, oids := range logs {
+		if len(oids) > 0 {
+			oldestOids = append(oldestOids, oids[len(oids)-1])
+		}
+	}
+	baseOids := []string{}
+	// git merge-base exits with 1 when the histories are unrelated, then the whole graph is read.
+	if base, err := connection.GetMergeBase(ctx, oldestOids); err == nil && len(SplitLines(base)) > 0 {
+		baseOids = append(baseOids, SplitLines(base)[0])
+	}
+
+	commits, err := connection.GetCommitGraph(ctx, headOids, baseOids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToCommitGraph(branchNames, tipOids, SplitLines(commits)), nil
+}
+
+func toBranchRefs(refs []string) ([]string, []string) {
+	refNames := []string{}
+	oids := []string{}
+	for _, ref := range refs {
+		refName, oid, found := strings.Cut(ref, ":")
+		if !found {
+			continue
+		}
+		refNames = append(refNames, refName)
+		oids = append(oids, oid)
+	}
+	return extractBranchNames(refNames), oids
+}
+
+// ToCommitGraph builds the graph from the branches, their tips, and the commits
+// printed by git rev-list --topo-order --parents.
+func ToCommitGraph(branchNames []string, tipOids []string, commits []string) *CommitGraph {
+	graph := &CommitGraph{branchNames, map[string][]uint64{}}
+	words := (len(branchNames) + 63) / 64
+
+	bits := func(oid string) []uint64 {
+		if _, ok := graph.containedIn[oid]; !ok {
+			graph.containedIn[oid] = make([]uint64, words)
+		}
+		return graph.containedIn[oid]
+	}
+
+	for i, oid := range tipOids {
+		bits(oid)[i/64] |= 1 << (i % 64)
+	}
+
+	// Children come before their parents in topological order,
+	// so each commit has all the branches of its children when it is reached.
+	for _, commit := range commits {
+		oids := strings.Fields(commit)
+		if len(oids) == 0 {
+			continue
+		}
+		childBits := bits(oids[0])
+		for _, parent := range oids[1:] {
+			parentBits := bits(parent)
+			for i := range parentBits {
+				parentBits[i] |= childBits[i]
+			}
+		}
+	}
+
+	return graph
+}
+
+// BranchNames returns the names of the branches that contain the commit, in the order of git branch --all.
+func (g *CommitGraph) BranchNames(oid string) []string {
+	results := []string{}
+	for i, word := range g.containedIn[oid] {
+		for j := 0; j < 64; j++ {
+			if word&(1<<j) != 0 {
+				results = append(results, g.branchNames[i*64+j])
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// DefaultJobs is the number of branches analyzed at once when Options.Jobs is not set.
+var DefaultJobs = runtime.NumCPU()
+
+// runJobs calls job for each index from 0 to n-1, running up to jobs of them at once.
+// It stops starting new jobs and cancels the running ones when a job fails or the
+// context is canceled, and returns the first error.
+func runJobs(ctx context.Context, jobs int, n int, job func(ctx context.Context, i int) error) error {
+	if jobs < 1 {
+		jobs = DefaultJobs
+	}
+	if jobs > n {
+		jobs = n
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if jobCtx.Err() != nil {
+					continue
+				}
+				if err := job(jobCtx, i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-jobCtx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	// The commands killed by Ctrl-C fail with "signal: killed", which hides the cause.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return firstErr
+}
@@ -0,0 +1,95 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// SchemaVersion is increased when the plan changes incompatibly.
+const SchemaVersion = 1
+
+type (
+	// Plan is the result of a dry run that can be applied later.
+	Plan struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		CreatedAt     string `json:"createdAt"`
+		// Checkout is the branch to switch to when the current branch is deleted.
+		Checkout string   `json:"checkout"`
+		Branches []Branch `json:"branches"`
+	}
+
+	Branch struct {
+		Name string `json:"name"`
+		// Oid is the analyzed tip. The branch is not deleted if it has moved.
+		Oid          string `json:"oid"`
+		PullRequests []int  `json:"pullRequests"`
+	}
+)
+
+// NewPlan records the deletable branches.
+func NewPlan(branches []shared.Branch, now time.Time) Plan {
+	plan := Plan{
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+		Branches:      []Branch{},
+	}
+	for _, branch := range branches {
+		if branch.Head && branch.State != shared.Deletable {
+			plan.Checkout = branch.Name
+		}
+		if branch.State != shared.Deletable {
+			continue
+		}
+
+		prNumbers := []int{}
+		for _, pr := range branch.PullRequests {
+			prNumbers = append(prNumbers, pr.Number)
+		}
+		plan.Branches = append(plan.Branches, Branch{branch.Name, branch.Oid, prNumbers})
+	}
+	return plan
+}
+
+func Parse(b []byte) (Plan, error) {
+	plan := Plan{}
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return Plan{}, fmt.Errorf("invalid plan: %w", err)
+	}
+	if plan.SchemaVersion != SchemaVersion {
+		return Plan{}, fmt.Errorf("unsupported plan schema version: %d", plan.SchemaVersion)
+	}
+	for _, branch := range plan.Branches {
+		if branch.Name == "" || branch.Oid == "" {
+			return Plan{}, fmt.Errorf("invalid plan: every branch needs a name and an oid")
+		}
+	}
+	return plan, nil
+}
+
+// ApplyPlan deletes exactly the planned branches, refusing those whose tips
+// have moved since planning.
+func ApplyPlan(ctx context.Context, plan Plan, connection shared.Connection) ([]shared.Branch, error) {
+	branches := []shared.Branch{}
+	for _, planned := range plan.Branches {
+		branches = append(branches, shared.Branch{
+			Name:  planned.Name,
+			Oid:   planned.Oid,
+			State: shared.Deletable,
+		})
+	}
+	if plan.Checkout != "" {
+		// DeleteBranches switches to the branch marked as the head when the current branch is deleted.
+		branches = append(branches, shared.Branch{
+			Head:  true,
+			Name:  plan.Checkout,
+			State: shared.NotDeletable,
+		})
+	}
+
+	return cmd.DeleteBranches(ctx, branches, connection)
+}
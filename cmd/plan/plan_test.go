@@ -0,0 +1,59 @@
+package plan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/seachicken/gh-poi/conn"
+	"github.com/seachicken/gh-poi/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewPlan(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", State: shared.Deletable,
+			PullRequests: []shared.PullRequest{{Number: 1}}},
+		{Head: true, Name: "main", Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", State: shared.NotDeletable},
+	}
+
+	actual := NewPlan(branches, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, Plan{
+		SchemaVersion: 1,
+		CreatedAt:     "2022-12-01T00:00:00Z",
+		Checkout:      "main",
+		Branches: []Branch{
+			{Name: "issue1", Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", PullRequests: []int{1}},
+		},
+	}, actual)
+}
+
+func Test_ParseReturnsAnErrorWhenTheSchemaVersionIsUnsupported(t *testing.T) {
+	_, err := Parse([]byte(`{"schemaVersion": 2, "branches": []}`))
+
+	assert.NotNil(t, err)
+}
+
+func Test_ApplyPlanDoesNotDeleteBranchesWhoseTipMoved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetWorktrees("main", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	actual, _ := ApplyPlan(context.Background(), Plan{
+		SchemaVersion: 1,
+		Checkout:      "main",
+		Branches: []Branch{
+			{Name: "issue1", Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e", PullRequests: []int{1}},
+		},
+	}, s.Conn)
+
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.TipMoved, actual[0].Reason)
+}
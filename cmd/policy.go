@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seachicken/gh-poi/shared"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Rules decide which branches are kept. They come from the policy file
+	// committed to the repository, or from the git config of each user.
+	Rules struct {
+		Protect      []string `yaml:"protect"`
+		MergeTargets []string `yaml:"mergeTargets"`
+		GracePeriod  string   `yaml:"gracePeriod"`
+		DeleteClosed *bool    `yaml:"deleteClosed"`
+	}
+
+	// Policy merges the rules of the repository with the rules of the user.
+	// Lists are combined, and the user's values win for the others.
+	Policy struct {
+		Repo Rules
+		User Rules
+		// Source is where the repository rules were read from.
+		Source string
+	}
+)
+
+const (
+	PolicyFilePath        = ".github/gh-poi.yml"
+	SettingsConfigPattern = `^gh-poi\.`
+	MergeTargetsConfigKey = "gh-poi.mergeTarget"
+	GracePeriodConfigKey  = "gh-poi.gracePeriod"
+	DeleteClosedConfigKey = "gh-poi.includeClosed"
+)
+
+// LoadPolicy reads the policy file from the working tree, or from the default
+// branch when the working tree has none, and the rules of the user from git config.
+func LoadPolicy(ctx context.Context, remote Remote, defaultBranchName string, connection shared.Connection) (Policy, error) {
+	policy := Policy{}
+
+	content, err := connection.GetWorkingTreeFile(ctx, PolicyFilePath)
+	if err == nil {
+		policy.Source = PolicyFilePath
+	} else {
+		content, err = connection.GetRefFile(ctx, fmt.Sprintf("%s/%s", remote.Name, defaultBranchName), PolicyFilePath)
+		if err == nil {
+			policy.Source = fmt.Sprintf("%s/%s:%s", remote.Name, defaultBranchName, PolicyFilePath)
+		}
+	}
+	if policy.Source != "" {
+		if policy.Repo, err = ParseRules(content); err != nil {
+			return Policy{}, fmt.Errorf("invalid %s: %w", policy.Source, err)
+		}
+	}
+
+	config, _ := connection.GetConfigRegexp(ctx, SettingsConfigPattern)
+	if policy.User, err = toUserRules(ToConfigEntries(SplitLines(config))); err != nil {
+		return Policy{}, err
+	}
+
+	return policy, nil
+}
+
+func ParseRules(content string) (Rules, error) {
+	rules := Rules{}
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	// A misspelled key would otherwise be ignored without a word.
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return Rules{}, err
+	}
+	if rules.GracePeriod != "" {
+		if _, err := ParseDuration(rules.GracePeriod); err != nil {
+			return Rules{}, fmt.Errorf("gracePeriod: %w", err)
+		}
+	}
+	return rules, nil
+}
+
+func toUserRules(entries []ConfigEntry) (Rules, error) {
+	rules := Rules{}
+	// git config prints variable names in lower case.
+	for _, entry := range entries {
+		switch {
+		case strings.EqualFold(entry.Key, ProtectPatternsConfigKey):
+			rules.Protect = append(rules.Protect, entry.Value)
+		case strings.EqualFold(entry.Key, MergeTargetsConfigKey):
+			rules.MergeTargets = append(rules.MergeTargets, entry.Value)
+		case strings.EqualFold(entry.Key, GracePeriodConfigKey):
+			if _, err := ParseDuration(entry.Value); err != nil {
+				return Rules{}, fmt.Errorf("%s: %w", GracePeriodConfigKey, err)
+			}
+			rules.GracePeriod = entry.Value
+		case strings.EqualFold(entry.Key, DeleteClosedConfigKey):
+			deleteClosed, err := strconv.ParseBool(entry.Value)
+			if err != nil {
+				return Rules{}, fmt.Errorf("%s: invalid boolean: %s", DeleteClosedConfigKey, entry.Value)
+			}
+			rules.DeleteClosed = &deleteClosed
+		}
+	}
+	return rules, nil
+}
+
+func (p Policy) ProtectPatterns() []string {
+	return union(p.User.Protect, p.Repo.Protect)
+}
+
+// IsRepoPattern returns true when only the repository policy has the pattern.
+func (p Policy) IsRepoPattern(pattern string) bool {
+	return nameExists(pattern, p.Repo.Protect) && !nameExists(pattern, p.User.Protect)
+}
+
+func (p Policy) MergeTargets() []string {
+	return union(p.User.MergeTargets, p.Repo.MergeTargets)
+}
+
+// IsRepoMergeTarget returns true when only the repository policy has the branch as a merge target.
+func (p Policy) IsRepoMergeTarget(branchName string) bool {
+	return nameExists(branchName, p.Repo.MergeTargets) && !nameExists(branchName, p.User.MergeTargets)
+}
+
+// GracePeriod returns how long to keep branches after their pull request is
+// closed, and whether it comes from the repository policy.
+func (p Policy) GracePeriod() (time.Duration, bool) {
+	if p.User.GracePeriod != "" {
+		d, _ := ParseDuration(p.User.GracePeriod)
+		return d, false
+	}
+	if p.Repo.GracePeriod != "" {
+		d, _ := ParseDuration(p.Repo.GracePeriod)
+		return d, true
+	}
+	return 0, false
+}
+
+// DeleteClosed returns whether branches with only closed pull requests can be deleted.
+func (p Policy) DeleteClosed() bool {
+	if p.User.DeleteClosed != nil {
+		return *p.User.DeleteClosed
+	}
+	if p.Repo.DeleteClosed != nil {
+		return *p.Repo.DeleteClosed
+	}
+	return false
+}
+
+func union(a []string, b []string) []string {
+	results := []string{}
+	for _, value := range append(append([]string{}, a...), b...) {
+		if !nameExists(value, results) {
+			results = append(results, value)
+		}
+	}
+	return results
+}
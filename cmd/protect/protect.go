@@ -3,43 +3,382 @@ package protect
 import (
 	"context"
 	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/seachicken/gh-poi/cmd"
 	"github.com/seachicken/gh-poi/shared"
 )
 
-func ProtectBranches(ctx context.Context, targetBranchNames []string, connection shared.Connection) error {
+type (
+	ResultState int
+
+	Result struct {
+		Name  string
+		State ResultState
+		// Pattern is the gh-poi.protect pattern that still protects the branch.
+		Pattern string
+	}
+)
+
+const (
+	Protected ResultState = iota
+	AlreadyProtected
+	Unprotected
+	NotProtected
+	NotFound
+	ProtectedByPattern
+)
+
+// CurrentBranch is the name that refers to the checked out branch.
+const CurrentBranch = "."
+
+// ProtectBranches protects the branches until the given time.
+// A zero time protects them without expiry. The note is recorded with the
+// time and the git user.name of the protection.
+func ProtectBranches(ctx context.Context, targetBranchNames []string, until time.Time, note string, connection shared.Connection) ([]Result, error) {
 	branchNameResults, err := connection.GetBranchNames(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	branches := cmd.ToBranch(cmd.SplitLines(branchNameResults))
 
-	for _, targetName := range targetBranchNames {
-		if cmd.BranchNameExists(targetName, branches) {
-			connection.RemoveConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", targetName))
-			_, err = connection.AddConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", targetName), "true")
-			if err != nil {
-				return err
+	results := []Result{}
+	for _, targetName := range resolveNames(targetBranchNames, branches) {
+		if !cmd.BranchNameExists(targetName, branches) {
+			results = append(results, Result{Name: targetName, State: NotFound})
+			continue
+		}
+		if isProtected(ctx, targetName, connection) {
+			if until.IsZero() && note == "" {
+				untilConfig, _ := connection.GetConfig(ctx, configKey(targetName, cmd.ProtectedUntilConfigVariable))
+				if cmd.ParseConfigTime(untilConfig).IsZero() {
+					results = append(results, Result{Name: targetName, State: AlreadyProtected})
+					continue
+				}
 			}
+			if err := updateMetadata(ctx, targetName, until, note, connection); err != nil {
+				return nil, err
+			}
+			results = append(results, Result{Name: targetName, State: Protected})
+			continue
+		}
+
+		removeProtection(ctx, targetName, connection)
+		_, err = connection.AddConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", targetName), "true")
+		if err != nil {
+			return nil, err
+		}
+		if err := addMetadata(ctx, targetName, until, note, connection); err != nil {
+			return nil, err
 		}
+		results = append(results, Result{Name: targetName, State: Protected})
 	}
 
+	return results, nil
+}
+
+func addMetadata(ctx context.Context, branchName string, until time.Time, note string, connection shared.Connection) error {
+	metadata := []cmd.ConfigEntry{
+		{Key: configKey(branchName, cmd.ProtectedAtConfigVariable), Value: time.Now().UTC().Format(time.RFC3339)},
+	}
+	if userName, _ := connection.GetConfig(ctx, "user.name"); len(cmd.SplitLines(userName)) > 0 {
+		metadata = append(metadata,
+			cmd.ConfigEntry{Key: configKey(branchName, cmd.ProtectionAuthorConfigVariable), Value: cmd.SplitLines(userName)[0]})
+	}
+	if !until.IsZero() {
+		metadata = append(metadata,
+			cmd.ConfigEntry{Key: configKey(branchName, cmd.ProtectedUntilConfigVariable), Value: until.UTC().Format(time.RFC3339)})
+	}
+	if note != "" {
+		metadata = append(metadata,
+			cmd.ConfigEntry{Key: configKey(branchName, cmd.ProtectionNoteConfigVariable), Value: note})
+	}
+
+	for _, entry := range metadata {
+		if _, err := connection.AddConfig(ctx, entry.Key, entry.Value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func UnprotectBranches(ctx context.Context, targetBranchNames []string, connection shared.Connection) error {
+// updateMetadata replaces only the given expiry and note of an existing protection,
+// keeping when and by whom it was made. When neither is given, the protection
+// no longer expires.
+func updateMetadata(ctx context.Context, branchName string, until time.Time, note string, connection shared.Connection) error {
+	if !until.IsZero() || note == "" {
+		connection.RemoveConfig(ctx, configKey(branchName, cmd.ProtectedUntilConfigVariable))
+		if !until.IsZero() {
+			if _, err := connection.AddConfig(ctx, configKey(branchName, cmd.ProtectedUntilConfigVariable), until.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+		}
+	}
+	if note != "" {
+		connection.RemoveConfig(ctx, configKey(branchName, cmd.ProtectionNoteConfigVariable))
+		if _, err := connection.AddConfig(ctx, configKey(branchName, cmd.ProtectionNoteConfigVariable), note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func configKey(branchName string, variable string) string {
+	return fmt.Sprintf("branch.%s.%s", branchName, variable)
+}
+
+func UnprotectBranches(ctx context.Context, targetBranchNames []string, connection shared.Connection) ([]Result, error) {
 	branchNameResults, err := connection.GetBranchNames(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	branches := cmd.ToBranch(cmd.SplitLines(branchNameResults))
 
-	for _, targetName := range targetBranchNames {
-		if cmd.BranchNameExists(targetName, branches) {
-			connection.RemoveConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", targetName))
+	patterns, err := getPatterns(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, targetName := range resolveNames(targetBranchNames, branches) {
+		// Stale protections of deleted branches can also be removed by name.
+		if isProtected(ctx, targetName, connection) {
+			removeProtection(ctx, targetName, connection)
+			results = append(results, Result{Name: targetName, State: Unprotected})
+		} else if pattern := cmd.MatchProtectPattern(targetName, patterns); pattern != "" && cmd.BranchNameExists(targetName, branches) {
+			results = append(results, Result{Name: targetName, State: ProtectedByPattern, Pattern: pattern})
+		} else if cmd.BranchNameExists(targetName, branches) {
+			results = append(results, Result{Name: targetName, State: NotProtected})
+		} else {
+			results = append(results, Result{Name: targetName, State: NotFound})
+		}
+	}
+
+	return results, nil
+}
+
+func resolveNames(targetBranchNames []string, branches []shared.Branch) []string {
+	results := []string{}
+	for _, name := range targetBranchNames {
+		if name == CurrentBranch {
+			for _, branch := range branches {
+				if branch.Head && !branch.IsDetached() {
+					name = branch.Name
+				}
+			}
+		}
+		results = append(results, name)
+	}
+	return results
+}
+
+// protectionMetadataVariables are stored next to gh-poi-protected and removed with it.
+var protectionMetadataVariables = []string{
+	cmd.ProtectedUntilConfigVariable,
+	cmd.ProtectionNoteConfigVariable,
+	cmd.ProtectedAtConfigVariable,
+	cmd.ProtectionAuthorConfigVariable,
+}
+
+func removeProtection(ctx context.Context, branchName string, connection shared.Connection) error {
+	_, err := connection.RemoveConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", branchName))
+	for _, variable := range protectionMetadataVariables {
+		connection.RemoveConfig(ctx, configKey(branchName, variable))
+	}
+	return err
+}
+
+func isProtected(ctx context.Context, branchName string, connection shared.Connection) bool {
+	config, _ := connection.GetConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", branchName))
+	splitConfig := cmd.SplitLines(config)
+	return len(splitConfig) > 0 && splitConfig[0] == "true"
+}
+
+// HasFailure reports whether any of the branch names was not found.
+func HasFailure(results []Result) bool {
+	for _, result := range results {
+		if result.State == NotFound {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ResultState) String() string {
+	switch s {
+	case Protected:
+		return "protected"
+	case AlreadyProtected:
+		return "already protected"
+	case Unprotected:
+		return "unprotected"
+	case NotProtected:
+		return "was not protected"
+	case NotFound:
+		return "not found"
+	case ProtectedByPattern:
+		return "protected by pattern"
+	default:
+		return ""
+	}
+}
+
+func (r Result) String() string {
+	if r.State == ProtectedByPattern {
+		return fmt.Sprintf("protected by pattern %q", r.Pattern)
+	}
+	return r.State.String()
+}
+
+// ProtectPatterns adds the patterns to the multi-valued gh-poi.protect config,
+// so that existing and future branches matching them are protected.
+func ProtectPatterns(ctx context.Context, patterns []string, connection shared.Connection) error {
+	existing, err := getPatterns(ctx, connection)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range patterns {
+		if err := validatePattern(pattern); err != nil {
+			return err
 		}
+		if containsPattern(pattern, existing) {
+			continue
+		}
+		if _, err := connection.AddConfig(ctx, cmd.ProtectPatternsConfigKey, pattern); err != nil {
+			return err
+		}
+		existing = append(existing, pattern)
 	}
 
 	return nil
 }
+
+func UnprotectPatterns(ctx context.Context, patterns []string, connection shared.Connection) error {
+	existing, err := getPatterns(ctx, connection)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range patterns {
+		if containsPattern(pattern, existing) {
+			if _, err := connection.RemoveConfigValue(ctx, cmd.ProtectPatternsConfigKey, pattern); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func getPatterns(ctx context.Context, connection shared.Connection) ([]string, error) {
+	config, _ := connection.GetConfigRegexp(ctx, cmd.ProtectPatternsConfigPattern)
+	results := []string{}
+	for _, entry := range cmd.ToConfigEntries(cmd.SplitLines(config)) {
+		results = append(results, entry.Value)
+	}
+	return results, nil
+}
+
+func validatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, cmd.RegexPatternPrefix) {
+		if _, err := regexp.Compile(strings.TrimPrefix(pattern, cmd.RegexPatternPrefix)); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+func containsPattern(pattern string, patterns []string) bool {
+	for _, p := range patterns {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+type ProtectedBranch struct {
+	Name string
+	// IsStale is true when the protected branch no longer exists locally.
+	IsStale bool
+	// Until is zero when the protection does not expire.
+	Until  time.Time
+	Note   string
+	At     time.Time
+	Author string
+}
+
+const protectedConfigPattern = `^branch\..+\.gh-poi-protected`
+
+// GetProtectedBranches reads all protected branches in a single git config call.
+func GetProtectedBranches(ctx context.Context, connection shared.Connection) ([]ProtectedBranch, error) {
+	branchNameResults, err := connection.GetBranchNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branches := cmd.ToBranch(cmd.SplitLines(branchNameResults))
+
+	config, _ := connection.GetConfigRegexp(ctx, protectedConfigPattern)
+	entries := cmd.ToConfigEntries(cmd.SplitLines(config))
+	results := []ProtectedBranch{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Key, ".gh-poi-protected") || entry.Value != "true" {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(entry.Key, "branch."), ".gh-poi-protected")
+		metadata := map[string]string{}
+		for _, variable := range protectionMetadataVariables {
+			for _, e := range entries {
+				if e.Key == configKey(name, variable) {
+					metadata[variable] = e.Value
+				}
+			}
+		}
+		results = append(results, ProtectedBranch{
+			Name:    name,
+			IsStale: !cmd.BranchNameExists(name, branches),
+			Until:   cmd.ParseConfigTime(metadata[cmd.ProtectedUntilConfigVariable]),
+			Note:    metadata[cmd.ProtectionNoteConfigVariable],
+			At:      cmd.ParseConfigTime(metadata[cmd.ProtectedAtConfigVariable]),
+			Author:  metadata[cmd.ProtectionAuthorConfigVariable],
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+func GetProtectPatterns(ctx context.Context, connection shared.Connection) ([]string, error) {
+	return getPatterns(ctx, connection)
+}
+
+// UnprotectAllBranches removes the protection of all branches, or only of the
+// stale ones, and returns the names of the unprotected branches.
+func UnprotectAllBranches(ctx context.Context, staleOnly bool, connection shared.Connection) ([]string, error) {
+	protectedBranches, err := GetProtectedBranches(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []string{}
+	for _, branch := range protectedBranches {
+		if staleOnly && !branch.IsStale {
+			continue
+		}
+		if err := removeProtection(ctx, branch.Name, connection); err != nil {
+			return nil, err
+		}
+		results = append(results, branch.Name)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,183 @@
+package protect
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/seachicken/gh-poi/conn"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetProtectedBranches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfigRegexp("protected", nil, conn.NewConf(&conn.Times{N: 1}))
+
+	actual, _ := GetProtectedBranches(context.Background(), s.Conn)
+
+	assert.Equal(t, []ProtectedBranch{
+		{Name: "issue1", IsStale: false, Until: time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC),
+			Note: "waiting on security review", At: time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC), Author: "octocat"},
+		{Name: "issue2", IsStale: true},
+	}, actual)
+}
+
+func Test_UnprotectOnlyStaleBranches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfigRegexp("protected", nil, nil).
+		RemoveConfig(nil, conn.NewConf(&conn.Times{N: 5}))
+
+	actual, _ := UnprotectAllBranches(context.Background(), true, s.Conn)
+
+	assert.Equal(t, []string{"issue2"}, actual)
+}
+
+func Test_ProtectBranchesReportsEachName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("main_@issue1", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "protected"},
+			{BranchName: "branch.main.gh-poi-protected-until", Filename: "empty"},
+			{BranchName: "user.name", Filename: "userName"},
+		}, nil, nil).
+		RemoveConfig(nil, nil).
+		AddConfig(nil, conn.NewConf(&conn.Times{N: 3}))
+
+	actual, _ := ProtectBranches(context.Background(), []string{".", "main", "relase/1.2"}, time.Time{}, "", s.Conn)
+
+	assert.Equal(t, []Result{
+		{Name: "issue1", State: Protected},
+		{Name: "main", State: AlreadyProtected},
+		{Name: "relase/1.2", State: NotFound},
+	}, actual)
+	assert.True(t, HasFailure(actual))
+}
+
+func Test_UnprotectBranchesReportsEachName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "protected"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue2.gh-poi-protected", Filename: "empty"},
+		}, nil, nil).
+		RemoveConfig(nil, conn.NewConf(&conn.Times{N: 5}))
+
+	actual, _ := UnprotectBranches(context.Background(), []string{"issue1", "main", "issue2"}, s.Conn)
+
+	assert.Equal(t, []Result{
+		{Name: "issue1", State: Unprotected},
+		{Name: "main", State: NotProtected},
+		{Name: "issue2", State: NotFound},
+	}, actual)
+}
+
+func Test_ProtectBranchesUntil(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+			{BranchName: "user.name", Filename: "empty"},
+		}, nil, nil).
+		RemoveConfig(nil, nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected", "true").Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-at", gomock.Any()).Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-until", "2026-12-01T00:00:00Z").Return("", nil)
+
+	actual, _ := ProtectBranches(context.Background(), []string{"issue1"}, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), "", s.Conn)
+
+	assert.Equal(t, []Result{{Name: "issue1", State: Protected}}, actual)
+}
+
+func Test_ProtectBranchesWithNote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+			{BranchName: "user.name", Filename: "userName"},
+		}, nil, nil).
+		RemoveConfig(nil, nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected", "true").Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-at", gomock.Any()).Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-author", "octocat").Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-note", "waiting on security review").Return("", nil)
+
+	actual, _ := ProtectBranches(context.Background(), []string{"issue1"}, time.Time{}, "waiting on security review", s.Conn)
+
+	assert.Equal(t, []Result{{Name: "issue1", State: Protected}}, actual)
+}
+
+func Test_ProtectAgainKeepsTheNoteOfTheProtection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "protected"},
+		}, nil, nil)
+	s.Conn.EXPECT().RemoveConfig(gomock.Any(), "branch.issue1.gh-poi-protected-until").Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-until", "2026-12-01T00:00:00Z").Return("", nil)
+
+	actual, _ := ProtectBranches(context.Background(), []string{"issue1"}, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), "", s.Conn)
+
+	assert.Equal(t, []Result{{Name: "issue1", State: Protected}}, actual)
+}
+
+func Test_ProtectAgainWithANoteKeepsTheExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "protected"},
+		}, nil, nil)
+	s.Conn.EXPECT().RemoveConfig(gomock.Any(), "branch.issue1.gh-poi-protected-note").Return("", nil)
+	s.Conn.EXPECT().AddConfig(gomock.Any(), "branch.issue1.gh-poi-protected-note", "waiting on security review").Return("", nil)
+
+	actual, _ := ProtectBranches(context.Background(), []string{"issue1"}, time.Time{}, "waiting on security review", s.Conn)
+
+	assert.Equal(t, []Result{{Name: "issue1", State: Protected}}, actual)
+}
+
+func Test_UnprotectBranchesReportsThePatternThatStillProtectsTheBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetConfigRegexp("protectPatterns", nil, nil).
+		GetConfig([]conn.ConfigStub{
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil).
+		RemoveConfig(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	actual, _ := UnprotectBranches(context.Background(), []string{"issue1"}, s.Conn)
+
+	assert.Equal(t, []Result{{Name: "issue1", State: ProtectedByPattern, Pattern: "issue*"}}, actual)
+	assert.Equal(t, `protected by pattern "issue*"`, actual[0].String())
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// maxPages bounds the pages followed for a search or the commits of a pull request.
+// GitHub returns up to 1,000 search results and 250 commits of a pull request, which fit in fewer pages.
+const maxPages = 10
+
+type (
+	searchPage struct {
+		PullRequests []shared.PullRequest
+		IssueCount   int
+		HasNextPage  bool
+		EndCursor    string
+	}
+
+	commitsPage struct {
+		Oids            []string
+		HasPreviousPage bool
+		StartCursor     string
+	}
+)
+
+// searchPullRequests returns the pull requests found by each of queryHashes, following the pages of the searches
+// that have more results.
+func searchPullRequests(ctx context.Context, hostname string, orgs string, repos string, queryHashes []string,
+	connection shared.Connection) ([]searchPage, error) {
+	results := make([]searchPage, len(queryHashes))
+	for i := range results {
+		results[i].PullRequests = []shared.PullRequest{}
+	}
+
+	pending := []int{}
+	cursors := []string{}
+	for i := range queryHashes {
+		pending = append(pending, i)
+		cursors = append(cursors, "")
+	}
+
+	for page := 0; len(pending) > 0 && page < maxPages; page++ {
+		pageHashes := []string{}
+		for _, i := range pending {
+			pageHashes = append(pageHashes, queryHashes[i])
+		}
+
+		json, err := connection.GetPullRequests(ctx, hostname, orgs, repos, pageHashes, cursors)
+		if err != nil {
+			return nil, err
+		}
+		pages, err := toPullRequests(json, len(pending))
+		if err != nil {
+			return nil, err
+		}
+
+		nextPending := []int{}
+		nextCursors := []string{}
+		for j, i := range pending {
+			results[i].PullRequests = append(results[i].PullRequests, pages[j].PullRequests...)
+			results[i].IssueCount = pages[j].IssueCount
+			if pages[j].HasNextPage {
+				nextPending = append(nextPending, i)
+				nextCursors = append(nextCursors, pages[j].EndCursor)
+			}
+		}
+		pending, cursors = nextPending, nextCursors
+	}
+
+	return results, nil
+}
+
+// applyOlderCommits fetches the commits before the last ones of the closed and merged pull requests
+// that do not have the local head of their branch, since they may have it in an older commit.
+func applyOlderCommits(ctx context.Context, hostname string, branches []shared.Branch, connection shared.Connection) ([]shared.Branch, error) {
+	commits := map[string][]string{}
+	cursors := map[string]string{}
+	pending := []string{}
+	for _, branch := range branches {
+		for _, pr := range branch.PullRequests {
+			if pr.State == shared.Open || pr.CommitsCursor == "" || containsLocalHead(branch, pr) {
+				continue
+			}
+			if _, ok := commits[pr.ID]; ok {
+				continue
+			}
+			commits[pr.ID] = pr.Commits
+			cursors[pr.ID] = pr.CommitsCursor
+			pending = append(pending, pr.ID)
+		}
+	}
+
+	for page := 0; len(pending) > 0 && page < maxPages; page++ {
+		pageCursors := []string{}
+		for _, id := range pending {
+			pageCursors = append(pageCursors, cursors[id])
+		}
+
+		json, err := connection.GetPullRequestCommits(ctx, hostname, pending, pageCursors)
+		if err != nil {
+			return nil, err
+		}
+		pages, err := toPullRequestCommits(json, len(pending))
+		if err != nil {
+			return nil, err
+		}
+
+		nextPending := []string{}
+		for i, id := range pending {
+			commits[id] = append(pages[i].Oids, commits[id]...)
+			cursors[id] = ""
+			if pages[i].HasPreviousPage {
+				cursors[id] = pages[i].StartCursor
+				nextPending = append(nextPending, id)
+			}
+		}
+		pending = nextPending
+	}
+
+	results := []shared.Branch{}
+	for _, branch := range branches {
+		branch.PullRequests = append([]shared.PullRequest{}, branch.PullRequests...)
+		for i, pr := range branch.PullRequests {
+			if oids, ok := commits[pr.ID]; ok {
+				trace(&branch, "fetched %d older %s of #%d", len(oids)-len(pr.Commits),
+					pluralize(len(oids)-len(pr.Commits), "commit"), pr.Number)
+				pr.Commits = oids
+				pr.CommitsCursor = cursors[pr.ID]
+				branch.PullRequests[i] = pr
+			}
+
+			if pr.State != shared.Open && !containsLocalHead(branch, pr) && len(pr.Commits) < pr.CommitCount {
+				warn(&branch, "only %d of %d commits of #%d were fetched, so the local head may be in the others",
+					len(pr.Commits), pr.CommitCount, pr.Number)
+			}
+		}
+		results = append(results, branch)
+	}
+
+	return results, nil
+}
+
+// toPullRequests returns a page of the pull requests found by each of the n aliased searches.
+func toPullRequests(jsonResp string, n int) ([]searchPage, error) {
+	type search struct {
+		IssueCount int
+		PageInfo   struct {
+			HasNextPage bool
+			EndCursor   string
+		}
+		Edges []struct {
+			Node struct {
+				Id          string
+				Number      int
+				HeadRefName string
+				HeadRefOid  string
+				Url         string
+				State       string
+				IsDraft     bool
+				ClosedAt    time.Time
+				Commits     struct {
+					TotalCount int
+					PageInfo   struct {
+						HasPreviousPage bool
+						StartCursor     string
+					}
+					Nodes []struct {
+						Commit struct {
+							Oid string
+						}
+					}
+				}
+				Author struct {
+					Login string
+				}
+			}
+		}
+	}
+	type response struct {
+		Data map[string]search
+	}
+
+	var resp response
+	if err := json.Unmarshal([]byte(jsonResp), &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	results := []searchPage{}
+	for i := 0; i < n; i++ {
+		search := resp.Data[shared.GetSearchAlias(i)]
+		prs := []shared.PullRequest{}
+		for _, edge := range search.Edges {
+			state, err := toPullRequestState(edge.Node.State)
+			if err == ErrNotFound {
+				return nil, fmt.Errorf("unexpected pull request state: %s", edge.Node.State)
+			}
+
+			commits := []string{}
+			for _, node := range edge.Node.Commits.Nodes {
+				commits = append(commits, node.Commit.Oid)
+			}
+			commitsCursor := ""
+			if edge.Node.Commits.PageInfo.HasPreviousPage {
+				commitsCursor = edge.Node.Commits.PageInfo.StartCursor
+			}
+
+			prs = append(prs, shared.PullRequest{
+				Name:          edge.Node.HeadRefName,
+				State:         state,
+				IsDraft:       edge.Node.IsDraft,
+				Number:        edge.Node.Number,
+				Commits:       commits,
+				Url:           edge.Node.Url,
+				Author:        edge.Node.Author.Login,
+				ClosedAt:      edge.Node.ClosedAt,
+				ID:            edge.Node.Id,
+				CommitCount:   edge.Node.Commits.TotalCount,
+				CommitsCursor: commitsCursor,
+			})
+		}
+		results = append(results, searchPage{
+			PullRequests: prs,
+			IssueCount:   search.IssueCount,
+			HasNextPage:  search.PageInfo.HasNextPage,
+			EndCursor:    search.PageInfo.EndCursor,
+		})
+	}
+
+	return results, nil
+}
+
+// toPullRequestCommits returns a page of the commits of each of the n aliased pull requests.
+func toPullRequestCommits(jsonResp string, n int) ([]commitsPage, error) {
+	type node struct {
+		Commits struct {
+			PageInfo struct {
+				HasPreviousPage bool
+				StartCursor     string
+			}
+			Nodes []struct {
+				Commit struct {
+					Oid string
+				}
+			}
+		}
+	}
+	type response struct {
+		Data map[string]node
+	}
+
+	var resp response
+	if err := json.Unmarshal([]byte(jsonResp), &resp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	results := []commitsPage{}
+	for i := 0; i < n; i++ {
+		commits := resp.Data[shared.GetCommitsAlias(i)].Commits
+		oids := []string{}
+		for _, node := range commits.Nodes {
+			oids = append(oids, node.Commit.Oid)
+		}
+		results = append(results, commitsPage{
+			Oids:            oids,
+			HasPreviousPage: commits.PageInfo.HasPreviousPage,
+			StartCursor:     commits.PageInfo.StartCursor,
+		})
+	}
+
+	return results, nil
+}
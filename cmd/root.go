@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/seachicken/gh-poi/shared"
@@ -25,6 +27,17 @@ type (
 		Y    string
 		Path string
 	}
+
+	// Options change how the branches are analyzed.
+	Options struct {
+		DryRun bool
+		// IncludeClosed overrides the policy for branches with only closed pull requests when set.
+		IncludeClosed *bool
+		// Jobs is the number of branches analyzed at once, DefaultJobs when it is 0.
+		Jobs int
+		// BranchNames limits the analysis to the branches when set.
+		BranchNames []string
+	}
 )
 
 const (
@@ -32,15 +45,34 @@ const (
 	localhost = "github.localhost"
 )
 
+const (
+	ProtectPatternsConfigKey       = "gh-poi.protect"
+	ProtectPatternsConfigPattern   = `^gh-poi\.protect$`
+	RegexPatternPrefix             = "regex:"
+	ProtectedConfigVariable        = "gh-poi-protected"
+	ProtectedUntilConfigVariable   = "gh-poi-protected-until"
+	ProtectionNoteConfigVariable   = "gh-poi-protected-note"
+	ProtectedAtConfigVariable      = "gh-poi-protected-at"
+	ProtectionAuthorConfigVariable = "gh-poi-protected-author"
+)
+
 var ErrNotFound = errors.New("not found")
 
 func GetRemote(ctx context.Context, connection shared.Connection) (Remote, error) {
+	return GetRemoteByName(ctx, "", connection)
+}
+
+// GetRemoteByName returns the named remote, or the primary remote when the name is empty.
+func GetRemoteByName(ctx context.Context, name string, connection shared.Connection) (Remote, error) {
 	remoteNames, err := connection.GetRemoteNames(ctx)
 	if err != nil {
 		return Remote{}, err
 	}
 
 	remotes := toRemotes(SplitLines(remoteNames))
+	if name != "" {
+		remotes = filterRemotes(remotes, name)
+	}
 	if remote, err := getPrimaryRemote(remotes); err == nil {
 		hostname := remote.Hostname
 		if config, err := connection.GetSshConfig(ctx, hostname); err == nil {
@@ -52,7 +84,7 @@ func GetRemote(ctx context.Context, connection shared.Connection) (Remote, error
 	}
 }
 
-func GetBranches(ctx context.Context, remote Remote, connection shared.Connection, dryRun bool) ([]shared.
+func GetBranches(ctx context.Context, remote Remote, connection shared.Connection, options Options) ([]shared.
 	Branch, error) {
 	var repoNames []string
 	var defaultBranchName string
@@ -70,7 +102,15 @@ func GetBranches(ctx context.Context, remote Remote, connection shared.Connectio
 		return nil, err
 	}
 
-	branches, err := loadBranches(ctx, remote, defaultBranchName, repoNames, connection)
+	policy, err := LoadPolicy(ctx, remote, defaultBranchName, connection)
+	if err != nil {
+		return nil, err
+	}
+	if options.IncludeClosed != nil {
+		policy.User.DeleteClosed = options.IncludeClosed
+	}
+
+	branches, err := loadBranches(ctx, remote, defaultBranchName, repoNames, policy, options.Jobs, options.BranchNames, connection)
 	if err != nil {
 		return nil, err
 	}
@@ -82,9 +122,9 @@ func GetBranches(ctx context.Context, remote Remote, connection shared.Connectio
 		return nil, err
 	}
 
-	branches = checkDeletion(branches, uncommittedChanges)
+	branches = checkDeletion(branches, uncommittedChanges, defaultBranchName, policy, time.Now())
 
-	branches, err = switchToDefaultBranchIfDeleted(ctx, branches, defaultBranchName, connection, dryRun)
+	branches, err = switchToDefaultBranchIfDeleted(ctx, branches, defaultBranchName, connection, options.DryRun)
 	if err != nil {
 		return nil, err
 	}
@@ -94,20 +134,28 @@ func GetBranches(ctx context.Context, remote Remote, connection shared.Connectio
 	return branches, nil
 }
 
-func loadBranches(ctx context.Context, remote Remote, defaultBranchName string, repoNames []string, connection shared.Connection) ([]shared.Branch, error) {
+func loadBranches(ctx context.Context, remote Remote, defaultBranchName string, repoNames []string, policy Policy, jobs int, branchNames []string, connection shared.Connection) ([]shared.Branch, error) {
 	var branches []shared.Branch
+	var config BranchConfig
 	if names, err := connection.GetBranchNames(ctx); err == nil {
-		branches = ToBranch(SplitLines(names))
+		branches = filterBranches(ToBranch(SplitLines(names)), branchNames)
 		mergedNames, err := connection.GetMergedBranchNames(ctx, remote.Name, defaultBranchName)
 		if err != nil {
 			return nil, err
 		}
 		branches = applyMerged(branches, extractMergedBranchNames(SplitLines(mergedNames)))
-		branches, err = applyProtected(ctx, branches, connection)
-		if err != nil {
-			return nil, err
+		for _, target := range policy.MergeTargets() {
+			if target == defaultBranchName {
+				continue
+			}
+			// The merge target may not exist on the remote yet.
+			if mergedNames, err := connection.GetMergedBranchNames(ctx, remote.Name, target); err == nil {
+				branches = applyMergeTarget(branches, target, extractMergedBranchNames(SplitLines(mergedNames)))
+			}
 		}
-		branches, err = applyCommits(ctx, remote, branches, defaultBranchName, connection)
+		config = LoadBranchConfig(ctx, connection)
+		branches = applyProtected(branches, policy, time.Now(), config)
+		branches, err = applyCommits(ctx, remote, branches, defaultBranchName, policy, config, jobs, connection)
 		if err != nil {
 			return nil, err
 		}
@@ -118,20 +166,26 @@ func loadBranches(ctx context.Context, remote Remote, defaultBranchName string,
 	prs := []shared.PullRequest{}
 	orgs := shared.GetQueryOrgs(repoNames)
 	repos := shared.GetQueryRepos(repoNames)
-	for _, queryHashes := range shared.GetQueryHashes(branches) {
-		json, err := connection.GetPullRequests(ctx, remote.Hostname, orgs, repos, queryHashes)
+	for _, batch := range shared.GetQueryBatches(shared.GetQueryHashes(branches)) {
+		searches, err := searchPullRequests(ctx, remote.Hostname, orgs, repos, batch, connection)
 		if err != nil {
 			return nil, err
 		}
-
-		pr, err := toPullRequests(json)
-		if err != nil {
-			return nil, err
+		for i, queryHashes := range batch {
+			prs = append(prs, searches[i].PullRequests...)
+			branches = traceSearch(branches, queryHashes, searches[i].PullRequests)
+			if len(searches[i].PullRequests) < searches[i].IssueCount {
+				branches = warnTruncatedSearch(branches, queryHashes, len(searches[i].PullRequests), searches[i].IssueCount)
+			}
 		}
-		prs = append(prs, pr...)
 	}
 
-	branches = applyPullRequest(ctx, branches, prs, connection)
+	branches = applyPullRequest(branches, prs, config)
+
+	branches, err := applyOlderCommits(ctx, remote.Hostname, branches, connection)
+	if err != nil {
+		return nil, err
+	}
 
 	return branches, nil
 }
@@ -160,6 +214,16 @@ func toRemotes(remoteNames []string) []Remote {
 	return results
 }
 
+func filterRemotes(remotes []Remote, name string) []Remote {
+	results := []Remote{}
+	for _, remote := range remotes {
+		if remote.Name == name {
+			results = append(results, remote)
+		}
+	}
+	return results
+}
+
 func getPrimaryRemote(remotes []Remote) (Remote, error) {
 	if len(remotes) == 0 {
 		return Remote{}, ErrNotFound
@@ -199,92 +263,210 @@ func applyMerged(branches []shared.Branch, mergedNames []string) []shared.Branch
 	results := []shared.Branch{}
 	for _, branch := range branches {
 		branch.IsMerged = nameExists(branch.Name, mergedNames)
+		if branch.IsMerged {
+			trace(&branch, "listed by git branch --merged, so it is merged into the default branch")
+		} else {
+			trace(&branch, "not listed by git branch --merged")
+		}
 		results = append(results, branch)
 	}
 	return results
 }
 
-func applyProtected(ctx context.Context, branches []shared.Branch, connection shared.Connection) ([]shared.Branch, error) {
+// applyMergeTarget marks the branches merged into a merge target other than the default branch.
+func applyMergeTarget(branches []shared.Branch, target string, mergedNames []string) []shared.Branch {
 	results := []shared.Branch{}
-
 	for _, branch := range branches {
-		config, _ := connection.GetConfig(ctx, fmt.Sprintf("branch.%s.gh-poi-protected", branch.Name))
-		splitConfig := SplitLines(config)
-		if len(splitConfig) > 0 && splitConfig[0] == "true" {
-			branch.IsProtected = true
+		if !branch.IsMerged && branch.Name != target && nameExists(branch.Name, mergedNames) {
+			branch.IsMerged = true
+			trace(&branch, "listed by git branch --merged for the merge target %s", target)
 		}
 		results = append(results, branch)
 	}
-
-	return results, nil
+	return results
 }
 
-func applyCommits(ctx context.Context, remote Remote, branches []shared.Branch, defaultBranchName string, connection shared.Connection) ([]shared.Branch, error) {
+func applyProtected(branches []shared.Branch, policy Policy, now time.Time, config BranchConfig) []shared.Branch {
 	results := []shared.Branch{}
 
+	patterns := policy.ProtectPatterns()
+
 	for _, branch := range branches {
+		if config.Get(branch.Name, ProtectedConfigVariable) == "true" {
+			branch.ProtectedUntil = ParseConfigTime(config.Get(branch.Name, ProtectedUntilConfigVariable))
+			branch.ProtectionNote = config.Get(branch.Name, ProtectionNoteConfigVariable)
+			branch.ProtectedAt = ParseConfigTime(config.Get(branch.Name, ProtectedAtConfigVariable))
+			branch.ProtectionAuthor = config.Get(branch.Name, ProtectionAuthorConfigVariable)
+			if branch.ProtectedUntil.IsZero() || now.Before(branch.ProtectedUntil) {
+				branch.IsProtected = true
+				trace(&branch, "protected by branch.%s.gh-poi-protected", branch.Name)
+			} else {
+				branch.ProtectionExpired = true
+				trace(&branch, "protection by branch.%s.gh-poi-protected expired at %s",
+					branch.Name, branch.ProtectedUntil.Format(time.RFC3339))
+			}
+		}
+
+		if !branch.IsProtected {
+			if pattern := MatchProtectPattern(branch.Name, patterns); pattern != "" {
+				branch.IsProtected = true
+				branch.ProtectedBy = pattern
+				if policy.IsRepoPattern(pattern) {
+					branch.KeptByPolicy = true
+					trace(&branch, "protected by the pattern %s in %s", pattern, policy.Source)
+				} else {
+					trace(&branch, "protected by the pattern %s in %s", pattern, ProtectPatternsConfigKey)
+				}
+			}
+		}
+		results = append(results, branch)
+	}
+
+	return results
+}
+
+// ParseConfigTime parses an RFC 3339 time stored with a protection, and
+// returns the zero time when it is not set.
+func ParseConfigTime(config string) time.Time {
+	splitConfig := SplitLines(config)
+	if len(splitConfig) == 0 {
+		return time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, splitConfig[0])
+	if err != nil {
+		return time.Time{}
+	}
+	return until
+}
+
+// MatchProtectPattern returns the first pattern that matches the branch name.
+// Patterns are globs, or regular expressions when prefixed with "regex:".
+func MatchProtectPattern(branchName string, patterns []string) string {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, RegexPatternPrefix) {
+			r, err := regexp.Compile(strings.TrimPrefix(pattern, RegexPatternPrefix))
+			if err == nil && r.MatchString(branchName) {
+				return pattern
+			}
+		} else if matched, _ := path.Match(pattern, branchName); matched {
+			return pattern
+		}
+	}
+	return ""
+}
+
+func applyCommits(ctx context.Context, remote Remote, branches []shared.Branch, defaultBranchName string, policy Policy, config BranchConfig, jobs int, connection shared.Connection) ([]shared.Branch, error) {
+	results := append([]shared.Branch{}, branches...)
+	// The logs are indexed like the results, and nil for the branches without commit history.
+	logs := make([][]string, len(branches))
+
+	// Each job only writes to its own branch and log, so the results keep the order of the branches.
+	err := runJobs(ctx, jobs, len(results), func(ctx context.Context, i int) error {
+		branch := &results[i]
+		if nameExists(branch.Name, policy.MergeTargets()) {
+			branch.Commits = []string{}
+			trace(branch, "skipped commit history of the merge target")
+			return nil
+		}
 		if branch.Name == defaultBranchName || branch.IsDetached() {
 			branch.Commits = []string{}
-			results = append(results, branch)
-			continue
+			trace(branch, "skipped commit history of the default branch or detached HEAD")
+			return nil
 		}
 
 		if remoteHeadOid, err := connection.GetRemoteHeadOid(ctx, remote.Name, branch.Name); err == nil {
 			branch.RemoteHeadOid = SplitLines(remoteHeadOid)[0]
+			trace(branch, "remote head oid %s resolved by git rev-parse %s/%s",
+				branch.RemoteHeadOid, remote.Name, branch.Name)
 		} else {
-			result, _ := connection.GetConfig(ctx, fmt.Sprintf("branch.%s.remote", branch.Name))
-			splitResults := SplitLines(result)
-			if len(splitResults) > 0 {
-				remoteUrl := splitResults[0]
+			if remoteUrl := config.Get(branch.Name, "remote"); remoteUrl != "" {
 				if result, err := connection.GetLsRemoteHeadOid(ctx, remoteUrl, branch.Name); err == nil {
 					splitResults := strings.Fields(result)
 					if len(splitResults) > 0 {
 						branch.RemoteHeadOid = splitResults[0]
 					}
 				}
+				if branch.RemoteHeadOid == "" {
+					trace(branch, "no remote head: %s/%s is not fetched and git ls-remote %s %s found nothing",
+						remote.Name, branch.Name, remoteUrl, branch.Name)
+				} else {
+					trace(branch, "remote head oid %s resolved by git ls-remote %s %s (%s/%s is not fetched)",
+						branch.RemoteHeadOid, remoteUrl, branch.Name, remote.Name, branch.Name)
+				}
+			} else {
+				trace(branch, "no remote head: %s/%s is not fetched and branch.%s.remote is not set",
+					remote.Name, branch.Name, branch.Name)
 			}
 		}
 
 		oids, err := connection.GetLog(ctx, branch.Name)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		logs[i] = SplitLines(oids)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		trimmedOids, err := trimBranch(
-			ctx, SplitLines(oids), branch.RemoteHeadOid, branch.IsMerged,
-			branch.Name, defaultBranchName, connection)
+	walkedLogs := [][]string{}
+	for i, branch := range results {
+		if needsWalk(branch, logs[i]) {
+			walkedLogs = append(walkedLogs, logs[i])
+		}
+	}
+
+	var graph *CommitGraph
+	if len(walkedLogs) > 0 {
+		graph, err = LoadCommitGraph(ctx, results, walkedLogs, connection)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		branch.Commits = trimmedOids
-		results = append(results, branch)
+	for i := range results {
+		if logs[i] == nil {
+			continue
+		}
+		trimmedOids, note := trimBranch(
+			logs[i], results[i].RemoteHeadOid, results[i].IsMerged,
+			results[i].Name, defaultBranchName, graph)
+		results[i].Commits = trimmedOids
+		trace(&results[i], "kept %d %s [%s]: %s",
+			len(trimmedOids), pluralize(len(trimmedOids), "commit"), strings.Join(trimmedOids, " "), note)
 	}
 
 	return results, nil
 }
 
-func trimBranch(ctx context.Context, oids []string, remoteHeadOid string, isMerged bool,
-	branchName string, defaultBranchName string, connection shared.Connection) ([]string, error) {
+// needsWalk tells whether trimBranch looks up the branches that contain the commits of the log.
+func needsWalk(branch shared.Branch, oids []string) bool {
+	return len(oids) > 0 && len(branch.RemoteHeadOid) == 0 && !branch.IsMerged
+}
+
+// trimBranch returns the commits that belong only to the branch, and a note
+// describing why it stopped walking the log.
+func trimBranch(oids []string, remoteHeadOid string, isMerged bool,
+	branchName string, defaultBranchName string, graph *CommitGraph) ([]string, string) {
 	results := []string{}
 	childNames := []string{}
 
 	for i, oid := range oids {
 		if len(remoteHeadOid) > 0 || isMerged {
 			results = append(results, oid)
-			break
+			if len(remoteHeadOid) > 0 {
+				return results, "only the tip is needed because the remote head is known"
+			}
+			return results, "only the tip is needed because it is merged into the default branch"
 		}
 
-		refNames, err := connection.GetAssociatedRefNames(ctx, oid)
-		if err != nil {
-			return nil, err
-		}
-		names := extractBranchNames(SplitLines(refNames))
+		names := graph.BranchNames(oid)
 
 		if i == 0 {
 			for _, name := range names {
 				if name == defaultBranchName {
-					return []string{}, nil
+					return []string{}, fmt.Sprintf("the tip %s is contained in %s", oid, name)
 				}
 				if name != branchName {
 					childNames = append(childNames, name)
@@ -303,14 +485,14 @@ func trimBranch(ctx context.Context, oids []string, remoteHeadOid string, isMerg
 
 		for _, name := range names {
 			if name != branchName && !isChild(name) {
-				return results, nil
+				return results, fmt.Sprintf("stopped at %s, which is contained in %s", oid, name)
 			}
 		}
 
 		results = append(results, oid)
 	}
 
-	return results, nil
+	return results, fmt.Sprintf("reached the end of the log after %d commits", len(oids))
 }
 
 func extractBranchNames(refNames []string) []string {
@@ -322,23 +504,33 @@ func extractBranchNames(refNames []string) []string {
 	return result
 }
 
-func applyPullRequest(ctx context.Context, branches []shared.Branch, prs []shared.PullRequest, connection shared.Connection) []shared.Branch {
-	prNumbers := map[string]int{}
+// filterBranches returns the branches with the names, keeping the detached HEAD
+// since it can contain the commits of the others. All branches are returned when
+// no names are given.
+func filterBranches(branches []shared.Branch, branchNames []string) []shared.Branch {
+	if len(branchNames) == 0 {
+		return branches
+	}
+	results := []shared.Branch{}
 	for _, branch := range branches {
-		if branch.IsDetached() {
-			continue
-		}
-		mergeConfig, _ := connection.GetConfig(ctx, fmt.Sprintf("branch.%s.merge", branch.Name))
-		if n := getPRNumber(mergeConfig); n > 0 {
-			prNumbers[branch.Name] = n
+		if branch.IsDetached() || nameExists(branch.Name, branchNames) {
+			results = append(results, branch)
 		}
 	}
+	return results
+}
+
+func applyPullRequest(branches []shared.Branch, prs []shared.PullRequest, config BranchConfig) []shared.Branch {
+	// The pull request numbers come from the config of all branches, including
+	// the ones not analyzed, so that a branch never takes the pull request of another.
+	prNumbers := config.PullRequestNumbers()
 
 	results := []shared.Branch{}
 	for _, branch := range branches {
-		prs := findMatchedPullRequest(branch.Name, prs, prNumbers)
-		sort.Slice(prs, func(i, j int) bool { return prs[i].Number < prs[j].Number })
-		branch.PullRequests = prs
+		matchedPrs := findMatchedPullRequest(branch.Name, prs, prNumbers)
+		sort.Slice(matchedPrs, func(i, j int) bool { return matchedPrs[i].Number < matchedPrs[j].Number })
+		branch.PullRequests = matchedPrs
+		traceMatch(&branch, prs, prNumbers)
 		results = append(results, branch)
 	}
 	return results
@@ -408,18 +600,37 @@ func toUncommittedChange(changes []string) []UncommittedChange {
 	return results
 }
 
-func checkDeletion(branches []shared.Branch, uncommittedChanges []UncommittedChange) []shared.Branch {
+func checkDeletion(branches []shared.Branch, uncommittedChanges []UncommittedChange, defaultBranchName string, policy Policy, now time.Time) []shared.Branch {
 	results := []shared.Branch{}
 	for _, branch := range branches {
-		branch.State = getDeleteStatus(branch, uncommittedChanges)
+		branch.State, branch.Reason = getDeleteStatus(branch, uncommittedChanges, defaultBranchName, policy, now)
+		switch branch.Reason {
+		case shared.MergeTarget:
+			branch.KeptByPolicy = policy.IsRepoMergeTarget(branch.Name)
+		case shared.WithinGracePeriod:
+			_, branch.KeptByPolicy = policy.GracePeriod()
+		}
+		traceDeleteStatus(&branch, policy.DeleteClosed())
 		results = append(results, branch)
 	}
 	return results
 }
 
-func getDeleteStatus(branch shared.Branch, uncommittedChanges []UncommittedChange) shared.BranchState {
+func getDeleteStatus(branch shared.Branch, uncommittedChanges []UncommittedChange, defaultBranchName string, policy Policy, now time.Time) (shared.BranchState, shared.BranchReason) {
 	if branch.IsProtected {
-		return shared.NotDeletable
+		return shared.NotDeletable, shared.ProtectedBranch
+	}
+
+	if branch.Name == defaultBranchName {
+		return shared.NotDeletable, shared.DefaultBranch
+	}
+
+	if nameExists(branch.Name, policy.MergeTargets()) {
+		return shared.NotDeletable, shared.MergeTarget
+	}
+
+	if branch.IsDetached() {
+		return shared.NotDeletable, shared.DetachedHead
 	}
 
 	hasTrackedChanges := false
@@ -430,31 +641,67 @@ func getDeleteStatus(branch shared.Branch, uncommittedChanges []UncommittedChang
 		}
 	}
 	if branch.Head && hasTrackedChanges {
-		return shared.NotDeletable
+		return shared.NotDeletable, shared.HasUncommittedChanges
 	}
 
 	if len(branch.PullRequests) == 0 {
-		return shared.NotDeletable
+		return shared.NotDeletable, shared.NoPullRequest
 	}
 
 	fullyMergedCnt := 0
+	fullyClosedCnt := 0
+	mergedCnt := 0
+	closedAt := time.Time{}
 	for _, pr := range branch.PullRequests {
 		if pr.State == shared.Open {
-			return shared.NotDeletable
+			return shared.NotDeletable, shared.HasOpenPullRequest
+		}
+		if pr.State == shared.Merged {
+			mergedCnt++
 		}
-		if isFullyMerged(branch, pr) {
+		if isFullyClosed(branch, pr) {
+			fullyClosedCnt++
+		}
+		if isFullyMerged(branch, pr) || (policy.DeleteClosed() && isFullyClosed(branch, pr)) {
 			fullyMergedCnt++
 		}
+		if pr.ClosedAt.After(closedAt) {
+			closedAt = pr.ClosedAt
+		}
+	}
+	// Only a closed pull request with the local head can be opted in, so that
+	// commits made after it are never offered for deletion.
+	if mergedCnt == 0 && !policy.DeleteClosed() && fullyClosedCnt > 0 {
+		return shared.NotDeletable, shared.OnlyClosedPullRequests
 	}
 	if fullyMergedCnt == 0 {
-		return shared.NotDeletable
+		return shared.NotDeletable, shared.NotFullyMerged
 	}
 
-	return shared.Deletable
+	if gracePeriod, _ := policy.GracePeriod(); !closedAt.IsZero() && now.Before(closedAt.Add(gracePeriod)) {
+		return shared.NotDeletable, shared.WithinGracePeriod
+	}
+
+	return shared.Deletable, shared.NoReason
 }
 
 func isFullyMerged(branch shared.Branch, pr shared.PullRequest) bool {
-	if pr.State != shared.Merged || len(branch.Commits) == 0 {
+	if pr.State != shared.Merged {
+		return false
+	}
+	return containsLocalHead(branch, pr)
+}
+
+// isFullyClosed returns true when the closed pull request has the local head of the branch.
+func isFullyClosed(branch shared.Branch, pr shared.PullRequest) bool {
+	if pr.State != shared.Closed {
+		return false
+	}
+	return containsLocalHead(branch, pr)
+}
+
+func containsLocalHead(branch shared.Branch, pr shared.PullRequest) bool {
+	if len(branch.Commits) == 0 {
 		return false
 	}
 
@@ -495,6 +742,7 @@ func switchToDefaultBranchIfDeleted(ctx context.Context, branches []shared.Branc
 		branch.Head = true
 		branch.Name = defaultBranchName
 		branch.State = shared.NotDeletable
+		branch.Reason = shared.DefaultBranch
 		results = append(results, branch)
 	}
 
@@ -518,6 +766,9 @@ func ToBranch(branchNames []string) []shared.Branch {
 		splitNames := strings.Split(branchName, ":")
 		branch.Head = splitNames[0] == "*"
 		branch.Name = splitNames[1]
+		if len(splitNames) > 2 {
+			branch.Oid = splitNames[2]
+		}
 		results = append(results, branch)
 	}
 
@@ -557,66 +808,6 @@ func getRepo(jsonResp string) ([]string, string, error) {
 	return repoNames, resp.DefaultBranchRef.Name, nil
 }
 
-func toPullRequests(jsonResp string) ([]shared.PullRequest, error) {
-	type response struct {
-		Data struct {
-			Search struct {
-				IssueCount int
-				Edges      []struct {
-					Node struct {
-						Number      int
-						HeadRefName string
-						HeadRefOid  string
-						Url         string
-						State       string
-						IsDraft     bool
-						Commits     struct {
-							Nodes []struct {
-								Commit struct {
-									Oid string
-								}
-							}
-						}
-						Author struct {
-							Login string
-						}
-					}
-				}
-			}
-		}
-	}
-
-	var resp response
-	if err := json.Unmarshal([]byte(jsonResp), &resp); err != nil {
-		return nil, fmt.Errorf("error unmarshaling response: %w", err)
-	}
-
-	results := []shared.PullRequest{}
-	for _, edge := range resp.Data.Search.Edges {
-		state, err := toPullRequestState(edge.Node.State)
-		if err == ErrNotFound {
-			return nil, fmt.Errorf("unexpected pull request state: %s", edge.Node.State)
-		}
-
-		commits := []string{}
-		for _, node := range edge.Node.Commits.Nodes {
-			commits = append(commits, node.Commit.Oid)
-		}
-
-		results = append(results, shared.PullRequest{
-			Name:    edge.Node.HeadRefName,
-			State:   state,
-			IsDraft: edge.Node.IsDraft,
-			Number:  edge.Node.Number,
-			Commits: commits,
-			Url:     edge.Node.Url,
-			Author:  edge.Node.Author.Login,
-		})
-	}
-
-	return results, nil
-}
-
 func toPullRequestState(state string) (shared.PullRequestState, error) {
 	switch state {
 	case "CLOSED":
@@ -630,13 +821,79 @@ func toPullRequestState(state string) (shared.PullRequestState, error) {
 	}
 }
 
+// IsSelectable reports whether the branch can be chosen for deletion by the user.
+// Branches whose only blocker is a closed pull request can be opted in.
+func IsSelectable(branch shared.Branch) bool {
+	return branch.State == shared.Deletable ||
+		(branch.State == shared.NotDeletable && branch.Reason == shared.OnlyClosedPullRequests)
+}
+
+// SelectBranches makes only the selected branches deletable.
+func SelectBranches(branches []shared.Branch, selectedNames []string) []shared.Branch {
+	results := []shared.Branch{}
+	for _, branch := range branches {
+		if IsSelectable(branch) {
+			if nameExists(branch.Name, selectedNames) {
+				branch.State = shared.Deletable
+				branch.Reason = shared.NoReason
+			} else if branch.State == shared.Deletable {
+				branch.State = shared.NotDeletable
+				branch.Reason = shared.Deselected
+			}
+		}
+		results = append(results, branch)
+	}
+	return results
+}
+
+// DeleteBranches deletes the deletable branches only when their tips are still
+// the analyzed ones, so that commits made after the analysis are never lost.
 func DeleteBranches(ctx context.Context, branches []shared.Branch, connection shared.Connection) ([]shared.Branch, error) {
+	branchNamesBefore, err := connection.GetBranchNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branchesBefore := ToBranch(SplitLines(branchNamesBefore))
+	if len(getBranchNames(branches, shared.Deletable)) == 0 {
+		// The analysis may have switched branches, so the heads are refreshed
+		// even when every branch was deselected.
+		return checkDeleted(branches, branchesBefore), nil
+	}
+	worktrees, err := connection.GetWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switchTo := getSwitchTarget(branches)
+	branches = refuseChangedBranches(branches, branchesBefore, extractWorktreeBranchNames(SplitLines(worktrees)), switchTo != "")
+
 	branchNames := getBranchNames(branches, shared.Deletable)
-	if len(branchNames) == 0 {
-		return branches, nil
+	for _, branch := range branchesBefore {
+		if !branch.Head || !nameExists(branch.Name, branchNames) {
+			continue
+		}
+		if _, err := connection.CheckoutBranch(ctx, switchTo); err != nil {
+			return nil, err
+		}
 	}
 
-	connection.DeleteBranches(ctx, branchNames)
+	backups, err := backupBranches(ctx, branchesBefore, branchNames, time.Now(), connection)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, branch := range branches {
+		if branch.State != shared.Deletable {
+			continue
+		}
+		if _, err := connection.DeleteBranch(ctx, branch.Name, branch.Oid); err != nil {
+			branches[i].State = shared.DeleteFailed
+			branches[i].DeleteError, branches[i].DeleteHints = toDeleteError(err)
+			trace(&branches[i], "failed to delete: %s", branches[i].DeleteError)
+			continue
+		}
+		// git update-ref leaves the config that git branch -D would remove.
+		connection.RemoveConfigSection(ctx, fmt.Sprintf("branch.%s", branch.Name))
+	}
 
 	branchNamesAfter, err := connection.GetBranchNames(ctx)
 	if err != nil {
@@ -644,7 +901,98 @@ func DeleteBranches(ctx context.Context, branches []shared.Branch, connection sh
 	}
 	branchesAfter := ToBranch(SplitLines(branchNamesAfter))
 
-	return checkDeleted(branches, branchesAfter), nil
+	branches = checkDeleted(branches, branchesAfter)
+	dropBackups(ctx, backups, branches, connection)
+	return branches, nil
+}
+
+// getSwitchTarget returns the branch to check out before deleting the current
+// branch: the one switched to by the analysis, or else the default branch,
+// since a branch opted in by the user was not switched from.
+func getSwitchTarget(branches []shared.Branch) string {
+	for _, branch := range branches {
+		if branch.Head && branch.State != shared.Deletable {
+			return branch.Name
+		}
+	}
+	for _, branch := range branches {
+		if branch.Reason == shared.DefaultBranch {
+			return branch.Name
+		}
+	}
+	return ""
+}
+
+// refuseChangedBranches makes the deletable branches not deletable when their
+// tips moved after the analysis or they cannot be checked out of.
+func refuseChangedBranches(branches []shared.Branch, branchesBefore []shared.Branch, worktreeBranchNames []string, canSwitch bool) []shared.Branch {
+	results := []shared.Branch{}
+	for _, branch := range branches {
+		if branch.State != shared.Deletable {
+			results = append(results, branch)
+			continue
+		}
+
+		var current *shared.Branch
+		for i := range branchesBefore {
+			if branchesBefore[i].Name == branch.Name {
+				current = &branchesBefore[i]
+			}
+		}
+		switch {
+		case current == nil:
+			branch.State, branch.Reason = shared.NotDeletable, shared.TipMoved
+			trace(&branch, "no longer exists")
+		case branch.Oid != "" && branch.Oid != current.Oid:
+			branch.State, branch.Reason = shared.NotDeletable, shared.TipMoved
+			trace(&branch, "the tip moved from %s to %s after the analysis", branch.Oid, current.Oid)
+		case !current.Head && nameExists(branch.Name, worktreeBranchNames):
+			branch.State, branch.Reason = shared.NotDeletable, shared.CheckedOutInWorktree
+			trace(&branch, "checked out in another worktree")
+		case current.Head && !canSwitch:
+			branch.State, branch.Reason = shared.NotDeletable, shared.NoSwitchTarget
+			trace(&branch, "checked out with no branch to switch to")
+		default:
+			branch.Oid = current.Oid
+		}
+		results = append(results, branch)
+	}
+	return results
+}
+
+func extractWorktreeBranchNames(worktrees []string) []string {
+	results := []string{}
+	for _, line := range worktrees {
+		if strings.HasPrefix(line, "branch refs/heads/") {
+			results = append(results, strings.TrimPrefix(line, "branch refs/heads/"))
+		}
+	}
+	return results
+}
+
+func toDeleteError(err error) (string, []string) {
+	var commandErr *shared.CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Message(), commandErr.Hints()
+	}
+	return err.Error(), nil
+}
+
+// HasDeleteFailure returns true when any branch failed to delete.
+func HasDeleteFailure(branches []shared.Branch) bool {
+	return len(getBranchNames(branches, shared.DeleteFailed)) > 0
+}
+
+// HasRefusedBranch returns true when any branch was not deleted because it
+// changed after the analysis or could not be checked out of.
+func HasRefusedBranch(branches []shared.Branch) bool {
+	for _, branch := range branches {
+		switch branch.Reason {
+		case shared.TipMoved, shared.CheckedOutInWorktree, shared.NoSwitchTarget:
+			return true
+		}
+	}
+	return false
 }
 
 func getBranchNames(branches []shared.Branch, state shared.BranchState) []string {
@@ -665,6 +1013,11 @@ func checkDeleted(branchesBefore []shared.Branch, branchesAfter []shared.Branch)
 				branch.State = shared.Deleted
 			}
 		}
+		for _, after := range branchesAfter {
+			if after.Name == branch.Name {
+				branch.Head = after.Head
+			}
+		}
 		results = append(results, branch)
 	}
 	return results
@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"errors"
+	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/seachicken/gh-poi/conn"
@@ -32,7 +34,9 @@ func Test_ShouldBeDeletableWhenRemoteBranchesAssociatedWithMergedPR(t *testing.T
 		}, nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -40,7 +44,7 @@ func Test_ShouldBeDeletableWhenRemoteBranchesAssociatedWithMergedPR(t *testing.T
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -69,7 +73,9 @@ func Test_ShouldBeDeletableWhenLsRemoteBranchesAssociatedWithMergedPR(t *testing
 		}, nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -78,7 +84,7 @@ func Test_ShouldBeDeletableWhenLsRemoteBranchesAssociatedWithMergedPR(t *testing
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -103,14 +109,14 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithMergedPR(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main_issue1Merged"}, {BranchName: "issue1", Filename: "issue1Merged"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e", Filename: "main_issue1"},
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "main_issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1Merged", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1Merged", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -119,7 +125,7 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithMergedPR(t *testing.T) {
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -144,13 +150,14 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithSquashAndMergedPR(t *testin
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -159,7 +166,7 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithSquashAndMergedPR(t *testin
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -168,6 +175,186 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithSquashAndMergedPR(t *testin
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
 
+func Test_FollowsPagesOfPullRequestSearch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, nil).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequestPages([]conn.PullRequestPageStub{
+			{Cursor: "", Filename: "issue1MergedPage1"},
+			{Cursor: "Y3Vyc29yOjE=", Filename: "issue1MergedPage2"},
+		}, nil, &conn.Conf{Times: &conn.Times{N: 1}}).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.Deletable, actual[0].State)
+	assert.Equal(t, 1, actual[0].PullRequests[0].Number)
+	assert.Empty(t, actual[0].Warnings)
+}
+
+func Test_WarnsWhenPullRequestSearchIsTruncated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, nil).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequests("issue1MergedTruncated", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t,
+		[]string{"only 1 of 2 pull requests found by hash:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0 were fetched, so a matching one may be missing"},
+		actual[0].Warnings,
+	)
+}
+
+func Test_ShouldBeDeletableWhenLocalHeadIsInOlderCommitsOfPR(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, nil).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequests("issue1MergedManyCommits", nil, nil).
+		GetPullRequestCommits("issue1", nil, &conn.Conf{Times: &conn.Times{N: 1}}).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.Deletable, actual[0].State)
+	assert.Equal(t,
+		[]string{
+			"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+			"b8a2645298053fb62ea03e27feea6c483d3fd27e",
+			"62d5d8280031f607f1db058da959a97f6a8e6d90",
+		},
+		actual[0].PullRequests[0].Commits,
+	)
+	assert.Empty(t, actual[0].Warnings)
+}
+
+func Test_WarnsWhenCommitsOfPRAreTruncated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, nil).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequests("issue1MergedTooManyCommits", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.NotFullyMerged, actual[0].Reason)
+	assert.Equal(t,
+		[]string{"only 2 of 300 commits of #1 were fetched, so the local head may be in the others"},
+		actual[0].Warnings,
+	)
+}
+
 func Test_ShouldBeDeletableWhenBranchesAssociatedWithUpstreamSquashAndMergedPR(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -184,13 +371,14 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithUpstreamSquashAndMergedPR(t
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1UpMerged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -199,7 +387,7 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithUpstreamSquashAndMergedPR(t
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -224,13 +412,14 @@ func Test_ShouldBeDeletableWhenPRCheckoutBranchesAssociatedWithUpstreamSquashAnd
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "fork/main", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "forkMain"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_forkMain"},
-		}, nil, nil).
+		GetBranchRefs("main_forkMain", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("forkMainUpMerged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.fork/main.merge", Filename: "mergeForkMain"},
@@ -239,7 +428,7 @@ func Test_ShouldBeDeletableWhenPRCheckoutBranchesAssociatedWithUpstreamSquashAnd
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "fork/main", actual[0].Name)
@@ -264,13 +453,14 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithTheCheckIsFalse(t *testing.
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -280,7 +470,7 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithTheCheckIsFalse(t *testing.
 		CheckoutBranch(nil, conn.NewConf(&conn.Times{N: 1}))
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -305,13 +495,14 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithTheCheckIsTrue(t *testing.T
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -321,7 +512,7 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithTheCheckIsTrue(t *testing.T
 		CheckoutBranch(nil, conn.NewConf(&conn.Times{N: 0}))
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, true)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{DryRun: true})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -346,13 +537,14 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithoutADefaultBranch(t *testin
 		GetLog([]conn.LogStub{
 			{BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "issue1_originMain"},
-		}, nil, nil).
+		GetBranchRefs("issue1_originMain", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
 			{BranchName: "branch.issue1.remote", Filename: "remote"},
 			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
@@ -360,7 +552,7 @@ func Test_ShouldBeDeletableWhenBranchIsCheckedOutWithoutADefaultBranch(t *testin
 		CheckoutBranch(nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -385,13 +577,14 @@ func Test_ShouldNotDeletableWhenBranchHasModifiedUncommittedChanges(t *testing.T
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges(" M README.md", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -401,11 +594,12 @@ func Test_ShouldNotDeletableWhenBranchHasModifiedUncommittedChanges(t *testing.T
 		CheckoutBranch(nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.HasUncommittedChanges, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
@@ -426,13 +620,14 @@ func Test_ShouldBeDeletableWhenBranchHasUntrackedUncommittedChanges(t *testing.T
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("?? new.txt", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -442,7 +637,7 @@ func Test_ShouldBeDeletableWhenBranchHasUntrackedUncommittedChanges(t *testing.T
 		CheckoutBranch(nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -467,13 +662,14 @@ func Test_ShouldNotDeletableWhenBranchesAssociatedWithClosedPR(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Closed", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -482,11 +678,12 @@ func Test_ShouldNotDeletableWhenBranchesAssociatedWithClosedPR(t *testing.T) {
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.OnlyClosedPullRequests, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
@@ -507,13 +704,14 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithSquashAndMergedAndClosedPRs
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged_issue1Closed", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -522,7 +720,7 @@ func Test_ShouldBeDeletableWhenBranchesAssociatedWithSquashAndMergedAndClosedPRs
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -547,15 +745,14 @@ func Test_ShouldNotDeletableWhenBranchesAssociatedWithNotFullyMergedPR(t *testin
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main_issue1SquashAndMerged"}, {BranchName: "issue1", Filename: "issue1CommitAfterMerge"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "cb197ba87e4ad323b1008c611212deb7da2a4a49", Filename: "main"},
-			{Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e", Filename: "issue1"},
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1CommitAfterSquashAndMerge", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1CommitAfterSquashAndMerge", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -564,11 +761,12 @@ func Test_ShouldNotDeletableWhenBranchesAssociatedWithNotFullyMergedPR(t *testin
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.NotFullyMerged, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
@@ -589,13 +787,14 @@ func Test_ShouldNotDeletableWhenDefaultBranchAssociatedWithMergedPR(t *testing.T
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("mainMerged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -604,13 +803,14 @@ func Test_ShouldNotDeletableWhenDefaultBranchAssociatedWithMergedPR(t *testing.T
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
+	assert.Equal(t, shared.DefaultBranch, actual[1].Reason)
 }
 
 func Test_ShouldNotDeletableWhenBranchIsProtected(t *testing.T) {
@@ -632,25 +832,350 @@ func Test_ShouldNotDeletableWhenBranchIsProtected(t *testing.T) {
 		}, nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
 			{BranchName: "branch.issue1.gh-poi-protected", Filename: "protected"},
+			{BranchName: "branch.issue1.gh-poi-protected-until", Filename: "empty"},
+			{BranchName: "branch.issue1.gh-poi-protected-note", Filename: "protectionNote"},
+			{BranchName: "branch.issue1.gh-poi-protected-at", Filename: "protectedAt"},
+			{BranchName: "branch.issue1.gh-poi-protected-author", Filename: "userName"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, true, actual[0].IsProtected)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.ProtectedBranch, actual[0].Reason)
+	assert.Equal(t, "waiting on security review", actual[0].ProtectionNote)
+	assert.Equal(t, time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC), actual[0].ProtectedAt)
+	assert.Equal(t, "octocat", actual[0].ProtectionAuthor)
+	assert.Equal(t, "main", actual[1].Name)
+	assert.Equal(t, false, actual[1].IsProtected)
+	assert.Equal(t, shared.NotDeletable, actual[1].State)
+}
+
+func Test_ShouldNotDeletableWhenProtectionHasNotExpired(t *testing.T) {
+	config := BranchConfig{
+		"branch.issue1.gh-poi-protected":       "true",
+		"branch.issue1.gh-poi-protected-until": "2999-01-01T00:00:00Z",
+	}
+
+	actual := applyProtected([]shared.Branch{{Name: "issue1"}}, Policy{}, time.Now(), config)
+
+	assert.Equal(t, true, actual[0].IsProtected)
+	assert.Equal(t, false, actual[0].ProtectionExpired)
+	assert.Equal(t, time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC), actual[0].ProtectedUntil)
+}
+
+func Test_DeletableWhenProtectionHasExpired(t *testing.T) {
+	config := BranchConfig{
+		"branch.issue1.gh-poi-protected":       "true",
+		"branch.issue1.gh-poi-protected-until": "2020-01-01T00:00:00Z",
+	}
+
+	actual := applyProtected([]shared.Branch{{Name: "issue1"}}, Policy{}, time.Now(), config)
+
+	assert.Equal(t, false, actual[0].IsProtected)
+	assert.Equal(t, true, actual[0].ProtectionExpired)
+}
+
+func Test_ShouldNotDeletableWhenBranchMatchesAProtectPattern(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main_issue1", nil, nil).
+		GetRemoteHeadOid([]conn.RemoteHeadStub{
+			{BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main_issue1Merged"}, {BranchName: "issue1", Filename: "issue1Merged"},
+		}, nil, nil).
+		GetPullRequests("issue1Merged", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("protectPatterns", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, true, actual[0].IsProtected)
+	assert.Equal(t, "issue*", actual[0].ProtectedBy)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.ProtectedBranch, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, false, actual[1].IsProtected)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
 
+func Test_ShouldNotDeletableWhenBranchMatchesARepoPolicyPattern(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main_issue1", nil, nil).
+		GetRemoteHeadOid([]conn.RemoteHeadStub{
+			{BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main_issue1Merged"}, {BranchName: "issue1", Filename: "issue1Merged"},
+		}, nil, nil).
+		GetPullRequests("issue1Merged", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("protectPatterns", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
+
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, "issue*", actual[0].ProtectedBy)
+	assert.Equal(t, true, actual[0].KeptByPolicy)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.ProtectedBranch, actual[0].Reason)
+	assert.Equal(t, false, actual[1].KeptByPolicy)
+}
+
+func Test_LoadsPolicyFromTheDefaultBranchWhenTheWorkingTreeHasNone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetWorkingTreeFile("empty", ErrCommand, nil).
+		GetRefFile("team", nil, nil).
+		GetConfigRegexp("protectPatterns", nil, nil)
+
+	actual, _ := LoadPolicy(context.Background(), Remote{Name: "origin"}, "main", s.Conn)
+
+	assert.Equal(t, "origin/main:.github/gh-poi.yml", actual.Source)
+	assert.Equal(t, []string{"release/*", "issue*"}, actual.ProtectPatterns())
+	assert.Equal(t, false, actual.IsRepoPattern("release/*"))
+	assert.Equal(t, []string{"develop"}, actual.MergeTargets())
+	assert.Equal(t, true, actual.DeleteClosed())
+	gracePeriod, fromRepo := actual.GracePeriod()
+	assert.Equal(t, 7*24*time.Hour, gracePeriod)
+	assert.Equal(t, true, fromRepo)
+}
+
+func Test_LoadsUserRulesFromLowerCaseGitConfigKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetWorkingTreeFile("empty", ErrCommand, nil).
+		GetRefFile("empty", ErrCommand, nil).
+		GetConfigRegexp("userRules", nil, nil)
+
+	actual, err := LoadPolicy(context.Background(), Remote{Name: "origin"}, "main", s.Conn)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", actual.Source)
+	assert.Equal(t, []string{"release/*"}, actual.ProtectPatterns())
+	assert.Equal(t, []string{"develop"}, actual.MergeTargets())
+	assert.Equal(t, true, actual.DeleteClosed())
+	gracePeriod, fromRepo := actual.GracePeriod()
+	assert.Equal(t, 3*24*time.Hour, gracePeriod)
+	assert.Equal(t, false, fromRepo)
+}
+
+func Test_ReturnsAnErrorWhenTheNamedRemoteDoesNotExist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil)
+
+	_, err := GetRemoteByName(context.Background(), "upstream", s.Conn)
+
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func Test_GetSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetConfigRegexpWithScope("settings", nil, nil)
+
+	actual, err := GetSettings(context.Background(), s.Conn)
+
+	assert.Nil(t, err)
+
+	assert.Contains(t, actual, Setting{Key: "gh-poi.dryRun", Flag: "dry-run", Value: "false", Source: "local"})
+	assert.Contains(t, actual, Setting{Key: "gh-poi.remote", Flag: "remote", Value: "upstream", Source: "local"})
+	assert.Contains(t, actual, Setting{Key: "gh-poi.prune", Flag: "prune", Value: "false", Source: "global"})
+	assert.Contains(t, actual, Setting{Key: "gh-poi.json", Flag: "json", Value: "false", Source: DefaultSource})
+}
+
+func Test_GetSettingsIgnoresNoMatchingKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetConfigRegexpWithScope("empty", &shared.CommandError{Name: "git", Err: exec.Command("sh", "-c", "exit 1").Run()}, nil)
+
+	actual, err := GetSettings(context.Background(), s.Conn)
+
+	assert.Nil(t, err)
+	assert.Contains(t, actual, Setting{Key: "gh-poi.prune", Flag: "prune", Value: "true", Source: DefaultSource})
+}
+
+func Test_GetSettingsReturnsTheDefaultsWhenGitConfigFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetConfigRegexpWithScope("empty", &shared.CommandError{Name: "git", Err: exec.Command("sh", "-c", "exit 129").Run()}, nil)
+
+	actual, err := GetSettings(context.Background(), s.Conn)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, actual, Setting{Key: "gh-poi.prune", Flag: "prune", Value: "true", Source: DefaultSource})
+}
+
+func Test_ParseRules(t *testing.T) {
+	_, err := ParseRules("gracePeriod: soon\n")
+	assert.NotNil(t, err)
+
+	_, err = ParseRules("protect: [\n")
+	assert.NotNil(t, err)
+
+	_, err = ParseRules("mergeTarget: [develop]\n")
+	assert.ErrorContains(t, err, "mergeTarget")
+
+	actual, _ := ParseRules("")
+	assert.Equal(t, Rules{}, actual)
+}
+
+func Test_CheckDeletionWithPolicy(t *testing.T) {
+	now := time.Date(2022, 12, 10, 0, 0, 0, 0, time.UTC)
+	deleteClosed := true
+	policy := Policy{
+		Repo: Rules{MergeTargets: []string{"develop"}, GracePeriod: "7d"},
+		User: Rules{DeleteClosed: &deleteClosed},
+	}
+	branches := []shared.Branch{
+		{Name: "develop", Commits: []string{}},
+		{Name: "issue1", Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+			PullRequests: []shared.PullRequest{
+				{Number: 1, State: shared.Merged, Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+					ClosedAt: time.Date(2022, 12, 5, 0, 0, 0, 0, time.UTC)},
+			}},
+		{Name: "issue2", Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+			PullRequests: []shared.PullRequest{
+				{Number: 2, State: shared.Closed, Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+					ClosedAt: time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)},
+			}},
+	}
+
+	actual := checkDeletion(branches, []UncommittedChange{}, "main", policy, now)
+
+	assert.Equal(t, shared.MergeTarget, actual[0].Reason)
+	assert.Equal(t, true, actual[0].KeptByPolicy)
+	assert.Equal(t, shared.WithinGracePeriod, actual[1].Reason)
+	assert.Equal(t, true, actual[1].KeptByPolicy)
+	assert.Equal(t, shared.Deletable, actual[2].State)
+	assert.Equal(t, false, actual[2].KeptByPolicy)
+}
+
+func Test_DoNotOfferBranchesWithCommitsAfterTheClosedPR(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+			PullRequests: []shared.PullRequest{
+				{Number: 1, State: shared.Closed, Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"}},
+			}},
+		{Name: "issue2", Commits: []string{"b8a2645298053fb62ea03e27feea6c483d3fd27e", "6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+			PullRequests: []shared.PullRequest{
+				{Number: 2, State: shared.Closed, Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"}},
+			}},
+	}
+
+	actual := checkDeletion(branches, []UncommittedChange{}, "main", Policy{}, time.Now())
+
+	assert.Equal(t, shared.OnlyClosedPullRequests, actual[0].Reason)
+	assert.True(t, IsSelectable(actual[0]))
+	assert.Equal(t, shared.NotFullyMerged, actual[1].Reason)
+	assert.False(t, IsSelectable(actual[1]))
+}
+
+func Test_TraceOnlyThePullRequestsFoundByTheHashOfTheBranch(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"}},
+		{Name: "issue2", Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"}},
+	}
+	prs := []shared.PullRequest{
+		{Number: 1, Name: "issue1", State: shared.Merged, Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"}},
+	}
+
+	actual := traceSearch(branches,
+		"hash:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0 hash:6ebe3d30d23531af56bd23b5a098d3ccae2a534a", prs)
+
+	assert.Equal(t, []string{
+		"searched pull requests by hash:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0, found #1 (issue1, merged)",
+	}, actual[0].Trace)
+	assert.Equal(t, []string{
+		"searched pull requests by hash:6ebe3d30d23531af56bd23b5a098d3ccae2a534a, found none",
+	}, actual[1].Trace)
+}
+
+func Test_TraceTheRuleThatMadeTheBranchDeletable(t *testing.T) {
+	deleteClosed := true
+	policy := Policy{User: Rules{DeleteClosed: &deleteClosed}}
+	branches := []shared.Branch{
+		{Name: "issue1", Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+			PullRequests: []shared.PullRequest{
+				{Number: 1, State: shared.Merged, Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"}},
+			}},
+		{Name: "issue2", Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+			PullRequests: []shared.PullRequest{
+				{Number: 2, State: shared.Closed, Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"}},
+			}},
+	}
+
+	actual := checkDeletion(branches, []UncommittedChange{}, "main", policy, time.Now())
+
+	assert.Equal(t, []string{
+		"#1 is merged and contains the local head a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+		"deletable because a pull request is fully merged",
+	}, actual[0].Trace)
+	assert.Equal(t, []string{
+		"#2 is closed and contains the local head 6ebe3d30d23531af56bd23b5a098d3ccae2a534a",
+		"deletable because a closed pull request contains the local head and closed pull requests are included",
+	}, actual[1].Trace)
+}
+
 func Test_BranchesAndPRsAreNotAssociatedWhenManyLocalCommitsAreAhead(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -668,14 +1193,14 @@ func Test_BranchesAndPRsAreNotAssociatedWhenManyLocalCommitsAreAhead(t *testing.
 			{BranchName: "main", Filename: "main"},
 			{BranchName: "issue1", Filename: "issue1ManyCommits"}, // return with '--max-count=3'
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "62d5d8280031f607f1db058da959a97f6a8e6d90", Filename: "issue1"},
-			{Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e", Filename: "issue1"},
-			{Oid: "d787669ee4a103fe0b361fe31c10ea037c72f27c", Filename: "issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1ManyCommits", nil, nil).
+		GetMergeBase("issue1ManyCommits", nil, nil).
+		GetCommitGraph("issue1ManyCommits", nil, nil).
 		GetPullRequests("notFound", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -684,12 +1209,13 @@ func Test_BranchesAndPRsAreNotAssociatedWhenManyLocalCommitsAreAhead(t *testing.
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
 	assert.Equal(t, []shared.PullRequest{}, actual[0].PullRequests)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.NoPullRequest, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
@@ -710,12 +1236,14 @@ func Test_ShouldBeNoCommitHistoryWhenTheFirstCommitOfATopicBranchIsAssociatedWit
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "main"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("notFound", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -724,7 +1252,7 @@ func Test_ShouldBeNoCommitHistoryWhenTheFirstCommitOfATopicBranchIsAssociatedWit
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "issue1", actual[0].Name)
@@ -750,28 +1278,78 @@ func Test_ShouldBeNoCommitHistoryWhenDetachedBranch(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("main", nil, nil).
 		GetPullRequests("notFound", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.(HEAD detached at a97e963).gh-poi-protected", Filename: "empty"},
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	actual, _ := GetBranches(context.Background(), remote, s.Conn, false)
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Equal(t, 2, len(actual))
 	assert.Equal(t, "(HEAD detached at a97e963)", actual[0].Name)
 	assert.Equal(t, []string{}, actual[0].Commits)
 	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.DetachedHead, actual[0].Reason)
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
 
+func Test_RecordsTheDecisionTraceOfEachBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		CheckRepos(nil, nil).
+		GetRemoteNames("origin", nil, nil).
+		GetSshConfig("github.com", nil, nil).
+		GetRepoNames("origin", nil, nil).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetMergedBranchNames("@main", nil, nil).
+		GetRemoteHeadOid(nil, ErrCommand, nil).
+		GetLsRemoteHeadOid(nil, nil, nil).
+		GetLog([]conn.LogStub{
+			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
+		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
+		GetPullRequests("issue1Merged", nil, nil).
+		GetUncommittedChanges("", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
+			{BranchName: "branch.main.merge", Filename: "mergeMain"},
+			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
+			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
+			{BranchName: "branch.issue1.remote", Filename: "remote"},
+			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
+		}, nil, nil)
+	remote, _ := GetRemote(context.Background(), s.Conn)
+
+	actual, _ := GetBranches(context.Background(), remote, s.Conn, Options{DryRun: true})
+
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, []string{
+		"not listed by git branch --merged",
+		"no remote head: origin/issue1 is not fetched and git ls-remote git@github.com:owner/repo.git issue1 found nothing",
+		"kept 1 commit [a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0]: " +
+			"stopped at 6ebe3d30d23531af56bd23b5a098d3ccae2a534a, which is contained in main",
+		"searched pull requests by hash:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0, found #1 (issue1, merged)",
+		"matched #1 by the head branch name \"issue1\"",
+		"#1 is merged and contains the local head a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+		"deletable because a pull request is fully merged",
+	}, actual[0].Trace)
+}
+
 func Test_ReturnsAnErrorWhenGetRemoteNamesFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -800,13 +1378,14 @@ func Test_DoesNotReturnsAnErrorWhenGetSshConfigFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -815,7 +1394,7 @@ func Test_DoesNotReturnsAnErrorWhenGetSshConfigFails(t *testing.T) {
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.Nil(t, err)
 }
@@ -830,7 +1409,7 @@ func Test_ReturnsAnErrorWhenGetRepoNamesFails(t *testing.T) {
 		GetRepoNames("origin", ErrCommand, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -846,7 +1425,7 @@ func Test_ReturnsAnErrorWhenCheckReposFails(t *testing.T) {
 		GetRepoNames("origin", nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -860,10 +1439,12 @@ func Test_ReturnsAnErrorWhenGetBranchNamesFails(t *testing.T) {
 		GetRemoteNames("origin", nil, nil).
 		GetSshConfig("github.com", nil, nil).
 		GetRepoNames("origin", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
 		GetBranchNames("@main_issue1", ErrCommand, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -877,11 +1458,13 @@ func Test_ReturnsAnErrorWhenGetMergedBranchNames(t *testing.T) {
 		GetRemoteNames("origin", nil, nil).
 		GetSshConfig("github.com", nil, nil).
 		GetRepoNames("origin", nil, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
 		GetBranchNames("@main_issue1", nil, nil).
 		GetMergedBranchNames("@main", ErrCommand, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -902,19 +1485,21 @@ func Test_ReturnsAnErrorWhenGetLogFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, ErrCommand, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.remote", Filename: "remote"},
 			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
 
-func Test_ReturnsAnErrorWhenGetAssociatedRefNamesFails(t *testing.T) {
+func Test_ReturnsAnErrorWhenGetCommitGraphFails(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -930,18 +1515,19 @@ func Test_ReturnsAnErrorWhenGetAssociatedRefNamesFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, ErrCommand, nil).
-		GetConfig([]conn.ConfigStub{
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", ErrCommand, nil).
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.remote", Filename: "remote"},
 			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -962,19 +1548,20 @@ func Test_ReturnsAnErrorWhenGetPullRequestsFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", ErrCommand, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.remote", Filename: "remote"},
 			{BranchName: "branch.issue1.gh-poi-protected", Filename: "empty"},
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -995,13 +1582,14 @@ func Test_ReturnsAnErrorWhenGetUncommittedChangesFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", ErrCommand, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -1010,7 +1598,7 @@ func Test_ReturnsAnErrorWhenGetUncommittedChangesFails(t *testing.T) {
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -1031,14 +1619,15 @@ func Test_ReturnsAnErrorWhenCheckoutBranchFails(t *testing.T) {
 		GetLog([]conn.LogStub{
 			{BranchName: "main", Filename: "main"}, {BranchName: "issue1", Filename: "issue1"},
 		}, nil, nil).
-		GetAssociatedRefNames([]conn.AssociatedBranchNamesStub{
-			{Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Filename: "issue1"},
-			{Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Filename: "main_issue1"},
-		}, nil, nil).
+		GetBranchRefs("main_issue1", nil, nil).
+		GetMergeBase("main", nil, nil).
+		GetCommitGraph("issue1", nil, nil).
 		GetPullRequests("issue1Merged", nil, nil).
 		GetUncommittedChanges("", nil, nil).
 		CheckoutBranch(ErrCommand, nil).
-		GetConfig([]conn.ConfigStub{
+		GetWorkingTreeFile("empty", nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetBranchConfig([]conn.ConfigStub{
 			{BranchName: "branch.main.merge", Filename: "mergeMain"},
 			{BranchName: "branch.main.gh-poi-protected", Filename: "empty"},
 			{BranchName: "branch.issue1.merge", Filename: "mergeIssue1"},
@@ -1047,7 +1636,7 @@ func Test_ReturnsAnErrorWhenCheckoutBranchFails(t *testing.T) {
 		}, nil, nil)
 	remote, _ := GetRemote(context.Background(), s.Conn)
 
-	_, err := GetBranches(context.Background(), remote, s.Conn, false)
+	_, err := GetBranches(context.Background(), remote, s.Conn, Options{})
 
 	assert.NotNil(t, err)
 }
@@ -1057,8 +1646,15 @@ func Test_DeletingDeletableBranches(t *testing.T) {
 	defer ctrl.Finish()
 
 	s := conn.Setup(ctrl).
-		GetBranchNames("@main", nil, nil).
-		DeleteBranches(nil, conn.NewConf(&conn.Times{N: 1}))
+		GetBranchNames("@main_issue1", nil, conn.NewConf(&conn.Times{N: 1})).
+		GetBranchNames("@main", nil, conn.NewConf(&conn.Times{N: 1})).
+		GetRefs("empty", nil, nil).
+		UpdateRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetConfigRegexp("issue1", nil, nil).
+		AddConfig(nil, conn.NewConf(&conn.Times{N: 2})).
+		GetWorktrees("main", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
 
 	branches := []shared.Branch{
 		{Head: false, Name: "issue1", IsMerged: false, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
@@ -1079,7 +1675,8 @@ func Test_DoNotDeleteNotDeletableBranches(t *testing.T) {
 	defer ctrl.Finish()
 
 	s := conn.Setup(ctrl).
-		DeleteBranches(nil, conn.NewConf(&conn.Times{N: 0}))
+		GetBranchNames("@main_issue1", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
 
 	branches := []shared.Branch{
 		{Head: false, Name: "issue1", IsMerged: false, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
@@ -1094,3 +1691,381 @@ func Test_DoNotDeleteNotDeletableBranches(t *testing.T) {
 	assert.Equal(t, "main", actual[1].Name)
 	assert.Equal(t, shared.NotDeletable, actual[1].State)
 }
+
+func Test_RefreshTheHeadWhenEveryBranchIsDeselected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("main_@issue1", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	branches := SelectBranches([]shared.Branch{
+		{Head: false, Name: "issue1", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}, []string{})
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, shared.Deselected, actual[0].Reason)
+	assert.Equal(t, true, actual[0].Head)
+	assert.Equal(t, false, actual[1].Head)
+}
+
+func Test_DoNotDeleteBranchesWhenBackupFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetWorktrees("main", nil, nil).
+		GetRefs("empty", nil, nil).
+		UpdateRef(ErrCommand, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	branches := []shared.Branch{
+		{Head: false, Name: "issue1", IsMerged: false, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", IsMerged: true, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}
+
+	_, err := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.NotNil(t, err)
+}
+
+func Test_DoNotOverwriteABackupWrittenInTheSameSecond(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1", nil, nil).
+		UpdateRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetConfigRegexp("empty", nil, nil)
+
+	branches := []shared.Branch{
+		{Name: "issue1", Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e"},
+	}
+
+	actual, _ := backupBranches(context.Background(), branches, []string{"issue1"}, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), s.Conn)
+
+	assert.Equal(t, "refs/gh-poi/trash/20220101T000000Z-2/issue1", actual[0].Ref)
+	assert.Equal(t, "20220101T000000Z-2", actual[0].Timestamp)
+}
+
+func Test_SwitchesToTheDefaultBranchBeforeDeletingTheCurrentBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("main_@issue1", nil, conn.NewConf(&conn.Times{N: 1})).
+		GetBranchNames("@main", nil, conn.NewConf(&conn.Times{N: 1})).
+		CheckoutBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetRefs("empty", nil, nil).
+		UpdateRef(nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetWorktrees("main", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	branches := []shared.Branch{
+		{Head: false, Name: "issue1", IsMerged: false, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", IsMerged: true, IsProtected: false, RemoteHeadOid: "", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.Deleted, actual[0].State)
+	assert.Equal(t, "main", actual[1].Name)
+	assert.Equal(t, true, actual[1].Head)
+}
+
+func Test_SwitchesToTheDefaultBranchBeforeDeletingTheOptedInCurrentBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("main_@issue1", nil, conn.NewConf(&conn.Times{N: 1})).
+		GetBranchNames("@main", nil, conn.NewConf(&conn.Times{N: 1})).
+		CheckoutBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetRefs("empty", nil, nil).
+		UpdateRef(nil, nil).
+		GetConfigRegexp("empty", nil, nil).
+		GetWorktrees("issue1", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	branches := SelectBranches([]shared.Branch{
+		{Head: true, Name: "issue1", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable, Reason: shared.OnlyClosedPullRequests},
+		{Head: false, Name: "main", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable, Reason: shared.DefaultBranch},
+	}, []string{"issue1"})
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.Deleted, actual[0].State)
+	assert.Equal(t, "main", actual[1].Name)
+	assert.Equal(t, true, actual[1].Head)
+}
+
+func Test_ReportBranchesThatFailedToDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetRefs("empty", nil, nil).
+		UpdateRef(nil, nil).
+		GetConfigRegexp("issue1", nil, nil).
+		AddConfig(nil, nil).
+		GetWorktrees("main", nil, nil).
+		DeleteBranch(&shared.CommandError{
+			Name: "git",
+			Stderr: "error: cannot lock ref 'refs/heads/issue1': Unable to create 'refs/heads/issue1.lock': File exists.\n" +
+				"hint: Remove the lock file if no other git process is running.\n",
+			Err: ErrCommand,
+		}, conn.NewConf(&conn.Times{N: 1})).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	branches := []shared.Branch{
+		{Head: false, Name: "issue1", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}
+
+	actual, err := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.DeleteFailed, actual[0].State)
+	assert.Equal(t, "cannot lock ref 'refs/heads/issue1': Unable to create 'refs/heads/issue1.lock': File exists.", actual[0].DeleteError)
+	assert.Equal(t, []string{"Remove the lock file if no other git process is running."}, actual[0].DeleteHints)
+	assert.True(t, HasDeleteFailure(actual))
+}
+
+func Test_DoNotDeleteBranchesWhoseTipMovedAfterTheAnalysis(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetWorktrees("main", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	branches := []shared.Branch{
+		{Head: false, Name: "issue1", Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, "issue1", actual[0].Name)
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.TipMoved, actual[0].Reason)
+	assert.True(t, HasRefusedBranch(actual))
+}
+
+func Test_DoNotDeleteBranchesCheckedOutInAnotherWorktree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("@main_issue1", nil, nil).
+		GetWorktrees("main_issue1", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	branches := []shared.Branch{
+		{Head: false, Name: "issue1", Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+		{Head: true, Name: "main", Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.NotDeletable},
+	}
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.CheckedOutInWorktree, actual[0].Reason)
+	assert.True(t, HasRefusedBranch(actual))
+}
+
+func Test_DoNotDeleteTheCurrentBranchWithNoBranchToSwitchTo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetBranchNames("main_@issue1", nil, nil).
+		GetWorktrees("issue1", nil, nil).
+		DeleteBranch(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	branches := []shared.Branch{
+		{Head: true, Name: "issue1", Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", Commits: []string{}, PullRequests: []shared.PullRequest{}, State: shared.Deletable},
+	}
+
+	actual, _ := DeleteBranches(context.Background(), branches, s.Conn)
+
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.NoSwitchTarget, actual[0].Reason)
+	assert.True(t, HasRefusedBranch(actual))
+}
+
+func Test_SelectBranches(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", State: shared.Deletable},
+		{Name: "issue2", State: shared.Deletable},
+		{Name: "issue3", State: shared.NotDeletable, Reason: shared.OnlyClosedPullRequests},
+		{Name: "issue4", State: shared.NotDeletable, Reason: shared.HasOpenPullRequest},
+	}
+
+	actual := SelectBranches(branches, []string{"issue2", "issue3", "issue4"})
+
+	assert.Equal(t, shared.NotDeletable, actual[0].State)
+	assert.Equal(t, shared.Deselected, actual[0].Reason)
+	assert.Equal(t, shared.Deletable, actual[1].State)
+	assert.Equal(t, shared.Deletable, actual[2].State)
+	assert.Equal(t, shared.NoReason, actual[2].Reason)
+	assert.Equal(t, shared.NotDeletable, actual[3].State)
+	assert.Equal(t, shared.HasOpenPullRequest, actual[3].Reason)
+}
+
+func Test_ParseDuration(t *testing.T) {
+	d, _ := ParseDuration("14d")
+	assert.Equal(t, 14*24*time.Hour, d)
+
+	d, _ = ParseDuration("2w")
+	assert.Equal(t, 14*24*time.Hour, d)
+
+	d, _ = ParseDuration("36h")
+	assert.Equal(t, 36*time.Hour, d)
+
+	_, err := ParseDuration("xd")
+	assert.NotNil(t, err)
+}
+
+func Test_ToBranchConfig(t *testing.T) {
+	config := ToBranchConfig(ToConfigEntries([]string{
+		"branch.Issue1.remote origin",
+		"branch.Issue1.merge refs/heads/Issue1",
+		"branch.Issue1.gh-poi-protected-note waiting on review",
+		"branch.Issue1.gh-poi-protected-note waiting on security review",
+	}))
+
+	assert.Equal(t, "origin", config.Get("Issue1", "remote"))
+	assert.Equal(t, "waiting on security review", config.Get("Issue1", ProtectionNoteConfigVariable))
+	assert.Equal(t, "", config.Get("issue1", "remote"))
+	assert.Equal(t, "", config.Get("Issue1", "gh-poi-protected"))
+}
+
+func Test_ToPullRequestsOfEachSearch(t *testing.T) {
+	actual, err := toPullRequests(`{
+  "data": {
+    "search0": {
+      "issueCount": 0,
+      "edges": []
+    },
+    "search1": {
+      "issueCount": 1,
+      "edges": [
+        {
+          "node": {
+            "number": 2,
+            "url": "https://github.com/owner/repo/pull/2",
+            "state": "MERGED",
+            "isDraft": false,
+            "headRefName": "issue2",
+            "commits": { "nodes": [{ "commit": { "oid": "b8a2645298053fb62ea03e27feea6c483d3fd27e" } }] },
+            "author": { "login": "owner" }
+          }
+        }
+      ]
+    }
+  }
+}`, 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, []shared.PullRequest{}, actual[0].PullRequests)
+	assert.Equal(t, 1, len(actual[1].PullRequests))
+	assert.Equal(t, "issue2", actual[1].PullRequests[0].Name)
+	assert.Equal(t, shared.Merged, actual[1].PullRequests[0].State)
+	assert.Equal(t, []string{"b8a2645298053fb62ea03e27feea6c483d3fd27e"}, actual[1].PullRequests[0].Commits)
+}
+
+func Test_RunJobs(t *testing.T) {
+	results := make([]int, 100)
+	err := runJobs(context.Background(), 4, len(results), func(ctx context.Context, i int) error {
+		results[i] = i * 2
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, results[0])
+	assert.Equal(t, 198, results[99])
+
+	calls := 0
+	err = runJobs(context.Background(), 1, 10, func(ctx context.Context, i int) error {
+		calls++
+		if i == 2 {
+			return ErrCommand
+		}
+		return nil
+	})
+	assert.Equal(t, ErrCommand, err)
+	assert.Equal(t, 3, calls)
+}
+
+func Test_RunJobsStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := runJobs(ctx, 2, 10, func(ctx context.Context, i int) error {
+		cancel()
+		<-ctx.Done()
+		return ErrCommand
+	})
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func Test_ToCommitGraph(t *testing.T) {
+	// * d (issue2)
+	// *   c (issue1) merges b
+	// |\
+	// | * b (origin/main)
+	// |/
+	// * a (main)
+	graph := ToCommitGraph(
+		[]string{"issue1", "issue2", "main", "main"},
+		[]string{"c", "d", "a", "b"},
+		[]string{
+			"d c",
+			"c a b",
+			"b a",
+			"a",
+		},
+	)
+
+	assert.Equal(t, []string{"issue2"}, graph.BranchNames("d"))
+	assert.Equal(t, []string{"issue1", "issue2"}, graph.BranchNames("c"))
+	assert.Equal(t, []string{"issue1", "issue2", "main"}, graph.BranchNames("b"))
+	assert.Equal(t, []string{"issue1", "issue2", "main", "main"}, graph.BranchNames("a"))
+	assert.Equal(t, []string{}, graph.BranchNames("e"))
+}
+
+func Test_ToConfigEntries(t *testing.T) {
+	assert.Equal(t,
+		[]ConfigEntry{
+			{"branch.fork/main.remote", "git@github.com:owner/repo.git"},
+			{"branch.fork/main.merge", "refs/pull/1/head"},
+		},
+		ToConfigEntries([]string{
+			"branch.fork/main.remote git@github.com:owner/repo.git",
+			"branch.fork/main.merge refs/pull/1/head",
+		}),
+	)
+}
+
+func Test_MatchProtectPattern(t *testing.T) {
+	patterns := []string{"release/*", "regex:^env/(dev|prd)$"}
+
+	assert.Equal(t, "release/*", MatchProtectPattern("release/1.2", patterns))
+	assert.Equal(t, "", MatchProtectPattern("release/1.2/hotfix", patterns))
+	assert.Equal(t, "regex:^env/(dev|prd)$", MatchProtectPattern("env/prd", patterns))
+	assert.Equal(t, "", MatchProtectPattern("env/stg", patterns))
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+// Setting is the default of a command line flag read from the gh-poi.* git config.
+type Setting struct {
+	Key   string
+	Flag  string
+	Value string
+	// Source is the git config scope the value was read from, such as
+	// "global" or "local", or DefaultSource when it is not set.
+	Source string
+}
+
+const DefaultSource = "default"
+
+var settingDefinitions = []Setting{
+	{Key: "gh-poi.dryRun", Flag: "dry-run", Value: "false"},
+	{Key: "gh-poi.json", Flag: "json", Value: "false"},
+	{Key: "gh-poi.yes", Flag: "yes", Value: "false"},
+	{Key: "gh-poi.debug", Flag: "debug", Value: "false"},
+	{Key: "gh-poi.remote", Flag: "remote", Value: ""},
+	{Key: DeleteClosedConfigKey, Flag: "include-closed", Value: "false"},
+	{Key: "gh-poi.prune", Flag: "prune", Value: "true"},
+	{Key: "gh-poi.jobs", Flag: "jobs", Value: strconv.Itoa(DefaultJobs)},
+}
+
+// GetSettings returns the effective default of each flag. As git does, the
+// value of the most specific scope wins. When git config fails, the built-in
+// defaults are returned with the error.
+func GetSettings(ctx context.Context, connection shared.Connection) ([]Setting, error) {
+	results := append([]Setting{}, settingDefinitions...)
+	for i := range results {
+		results[i].Source = DefaultSource
+	}
+
+	config, err := connection.GetConfigRegexpWithScope(ctx, SettingsConfigPattern)
+	// git config exits with 1 when no key matches, and with 129 when it is
+	// older than 2.26 and does not know --show-scope.
+	var commandErr *shared.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && commandErr.ExitCode() == 1) {
+		return results, err
+	}
+	for _, line := range SplitLines(config) {
+		scope, entry, found := strings.Cut(line, "\t")
+		if !found {
+			continue
+		}
+		configEntry := ToConfigEntries([]string{entry})[0]
+		for i := range results {
+			if strings.EqualFold(results[i].Key, configEntry.Key) {
+				results[i].Value = configEntry.Value
+				results[i].Source = scope
+			}
+		}
+	}
+
+	return results, nil
+}
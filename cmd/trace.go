@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+func trace(branch *shared.Branch, format string, args ...interface{}) {
+	branch.Trace = append(branch.Trace, fmt.Sprintf(format, args...))
+}
+
+// warn records that the result of the branch may be wrong, and traces it as well.
+func warn(branch *shared.Branch, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	branch.Warnings = append(branch.Warnings, message)
+	trace(branch, "warning: %s", message)
+}
+
+// traceSearch traces the pull requests found for each branch, that is, those
+// with the commit the branch was searched by.
+func traceSearch(branches []shared.Branch, queryHashes string, prs []shared.PullRequest) []shared.Branch {
+	results := []shared.Branch{}
+	for _, branch := range branches {
+		oid := branch.QueryOid()
+		if oid != "" && strings.Contains(queryHashes, "hash:"+oid) {
+			found := []string{}
+			for _, pr := range prs {
+				if nameExists(oid, pr.Commits) {
+					found = append(found, fmt.Sprintf("#%d (%s, %s)", pr.Number, pr.Name, pr.State))
+				}
+			}
+			if len(found) == 0 {
+				trace(&branch, "searched pull requests by hash:%s, found none", oid)
+			} else {
+				trace(&branch, "searched pull requests by hash:%s, found %s", oid, strings.Join(found, ", "))
+			}
+		}
+		results = append(results, branch)
+	}
+	return results
+}
+
+func warnTruncatedSearch(branches []shared.Branch, queryHashes string, found int, total int) []shared.Branch {
+	results := []shared.Branch{}
+	for _, branch := range branches {
+		oid := branch.QueryOid()
+		if oid != "" && strings.Contains(queryHashes, "hash:"+oid) {
+			warn(&branch, "only %d of %d pull requests found by hash:%s were fetched, so a matching one may be missing",
+				found, total, oid)
+		}
+		results = append(results, branch)
+	}
+	return results
+}
+
+func traceMatch(branch *shared.Branch, prs []shared.PullRequest, prNumbers map[string]int) {
+	if n, ok := prNumbers[branch.Name]; ok {
+		trace(branch, "branch.%s.merge is refs/pull/%d", branch.Name, n)
+	}
+
+	for _, pr := range branch.PullRequests {
+		if pr.Number == prNumbers[branch.Name] {
+			trace(branch, "matched #%d by the merge config", pr.Number)
+		} else {
+			trace(branch, "matched #%d by the head branch name %q", pr.Number, pr.Name)
+		}
+	}
+
+	for _, pr := range prs {
+		if pr.Name != branch.Name || pr.Number == prNumbers[branch.Name] {
+			continue
+		}
+		for name, n := range prNumbers {
+			if n == pr.Number && name != branch.Name {
+				trace(branch, "skipped #%d because it is checked out as %s", pr.Number, name)
+			}
+		}
+	}
+
+	if len(branch.PullRequests) == 0 && len(branch.Commits) > 0 {
+		trace(branch, "no pull requests matched")
+	}
+}
+
+func traceDeleteStatus(branch *shared.Branch, deleteClosed bool) {
+	fullyMerged := false
+	for _, pr := range branch.PullRequests {
+		switch {
+		case pr.State == shared.Closed && deleteClosed && isFullyClosed(*branch, pr):
+			trace(branch, "#%d is closed and contains the local head %s", pr.Number, branch.Commits[0])
+		case pr.State != shared.Merged:
+			trace(branch, "#%d is %s", pr.Number, pr.State)
+		case len(branch.Commits) == 0:
+			trace(branch, "#%d is merged, but the branch has no commits to compare", pr.Number)
+		case isFullyMerged(*branch, pr):
+			fullyMerged = true
+			trace(branch, "#%d is merged and contains the local head %s", pr.Number, branch.Commits[0])
+		default:
+			trace(branch, "#%d is merged, but the local head %s is not in its last %d %s",
+				pr.Number, branch.Commits[0], len(pr.Commits), pluralize(len(pr.Commits), "commit"))
+		}
+	}
+
+	// The reason of not deletable branches is added by the caller in the words of the listing.
+	if branch.State != shared.Deletable {
+		return
+	}
+	if fullyMerged {
+		trace(branch, "deletable because a pull request is fully merged")
+	} else {
+		trace(branch, "deletable because a closed pull request contains the local head and closed pull requests are included")
+	}
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
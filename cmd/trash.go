@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+type (
+	// TrashEntry is a backup of a deleted branch.
+	TrashEntry struct {
+		Ref       string
+		Timestamp string
+		Name      string
+		Oid       string
+	}
+
+	ConfigEntry struct {
+		Key   string
+		Value string
+	}
+)
+
+const (
+	TrashRefPrefix     = "refs/gh-poi/trash/"
+	TrashConfigSection = "gh-poi-trash"
+	trashTimeLayout    = "20060102T150405Z"
+)
+
+// backupBranches keeps the tip and the config of each branch under the trash
+// namespace, so that they can be restored after deletion. It returns the backups written.
+func backupBranches(ctx context.Context, branches []shared.Branch, branchNames []string, now time.Time, connection shared.Connection) ([]TrashEntry, error) {
+	if len(branchNames) == 0 {
+		return []TrashEntry{}, nil
+	}
+
+	entries, err := GetTrashEntries(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := uniqueTimestamp(now, entries)
+	results := []TrashEntry{}
+	for _, branch := range branches {
+		if !nameExists(branch.Name, branchNames) || branch.Oid == "" {
+			continue
+		}
+
+		entry := TrashEntry{TrashRefPrefix + timestamp + "/" + branch.Name, timestamp, branch.Name, branch.Oid}
+		if _, err := connection.UpdateRef(ctx, entry.Ref, entry.Oid); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", branch.Name, err)
+		}
+		results = append(results, entry)
+
+		configs, _ := connection.GetConfigRegexp(ctx, BranchConfigPattern(branch.Name))
+		for _, config := range ToConfigEntries(SplitLines(configs)) {
+			key := fmt.Sprintf("%s.%s.%s", TrashConfigSection, entry.Subsection(), ConfigVariable(config.Key))
+			if _, err := connection.AddConfig(ctx, key, config.Value); err != nil {
+				return nil, fmt.Errorf("failed to back up %s: %w", branch.Name, err)
+			}
+		}
+	}
+	return results, nil
+}
+
+// dropBackups removes the backups of the branches that were not deleted,
+// so that restoring the last run does not try to recreate them.
+func dropBackups(ctx context.Context, entries []TrashEntry, branches []shared.Branch, connection shared.Connection) {
+	deletedNames := getBranchNames(branches, shared.Deleted)
+	for _, entry := range entries {
+		if !nameExists(entry.Name, deletedNames) {
+			RemoveTrashEntry(ctx, entry, connection)
+		}
+	}
+}
+
+// RemoveTrashEntry removes the backed up tip and config of the branch.
+func RemoveTrashEntry(ctx context.Context, entry TrashEntry, connection shared.Connection) error {
+	if _, err := connection.DeleteRef(ctx, entry.Ref); err != nil {
+		return err
+	}
+	connection.RemoveConfigSection(ctx, fmt.Sprintf("%s.%s", TrashConfigSection, entry.Subsection()))
+	return nil
+}
+
+// GetTrashEntries returns the backups sorted from oldest to newest.
+func GetTrashEntries(ctx context.Context, connection shared.Connection) ([]TrashEntry, error) {
+	refs, err := connection.GetRefs(ctx, TrashRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []TrashEntry{}
+	for _, line := range SplitLines(refs) {
+		splitLine := strings.Split(line, ":")
+		if len(splitLine) != 2 {
+			continue
+		}
+		splitRef := strings.SplitN(strings.TrimPrefix(splitLine[0], TrashRefPrefix), "/", 2)
+		if len(splitRef) != 2 {
+			continue
+		}
+		results = append(results, TrashEntry{splitLine[0], splitRef[0], splitRef[1], splitLine[1]})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].before(results[j]) })
+	return results, nil
+}
+
+// uniqueTimestamp returns the timestamp of now, with a suffix when a backup of
+// another run in the same second already uses it.
+func uniqueTimestamp(now time.Time, entries []TrashEntry) string {
+	timestamp := now.UTC().Format(trashTimeLayout)
+	result := timestamp
+	for n := 2; timestampExists(result, entries); n++ {
+		result = fmt.Sprintf("%s-%d", timestamp, n)
+	}
+	return result
+}
+
+func timestampExists(timestamp string, entries []TrashEntry) bool {
+	for _, entry := range entries {
+		if entry.Timestamp == timestamp {
+			return true
+		}
+	}
+	return false
+}
+
+// BranchConfigPattern returns the pattern for git config --get-regexp that
+// matches all variables of the branch.
+func BranchConfigPattern(branchName string) string {
+	return fmt.Sprintf(`^branch\.%s\.`, regexp.QuoteMeta(branchName))
+}
+
+// ToConfigEntries parses the output of git config --get-regexp.
+func ToConfigEntries(lines []string) []ConfigEntry {
+	results := []ConfigEntry{}
+	for _, line := range lines {
+		splitLine := strings.SplitN(line, " ", 2)
+		value := ""
+		if len(splitLine) > 1 {
+			value = splitLine[1]
+		}
+		results = append(results, ConfigEntry{splitLine[0], value})
+	}
+	return results
+}
+
+// ConfigVariable returns the last part of the config key.
+func ConfigVariable(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+func (e TrashEntry) Subsection() string {
+	return e.Timestamp + "/" + e.Name
+}
+
+// ConfigPattern returns the pattern for git config --get-regexp that matches
+// the backed up variables of the branch.
+func (e TrashEntry) ConfigPattern() string {
+	return fmt.Sprintf(`^%s\.%s\.`, TrashConfigSection, regexp.QuoteMeta(e.Subsection()))
+}
+
+func (e TrashEntry) Time() (time.Time, error) {
+	timestamp, _, _ := strings.Cut(e.Timestamp, "-")
+	return time.Parse(trashTimeLayout, timestamp)
+}
+
+// before reports whether the backup was written by an earlier run than the other.
+func (e TrashEntry) before(other TrashEntry) bool {
+	timestamp, suffix, _ := strings.Cut(e.Timestamp, "-")
+	otherTimestamp, otherSuffix, _ := strings.Cut(other.Timestamp, "-")
+	if timestamp != otherTimestamp {
+		return timestamp < otherTimestamp
+	}
+	if len(suffix) != len(otherSuffix) {
+		return len(suffix) < len(otherSuffix)
+	}
+	return suffix < otherSuffix
+}
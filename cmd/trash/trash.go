@@ -0,0 +1,109 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/shared"
+)
+
+type (
+	RestoreResult struct {
+		Name string
+		Oid  string
+		Err  error
+	}
+)
+
+func ListTrash(ctx context.Context, connection shared.Connection) ([]cmd.TrashEntry, error) {
+	return cmd.GetTrashEntries(ctx, connection)
+}
+
+// EmptyTrash removes the backups older than the given age. A zero age removes all backups.
+func EmptyTrash(ctx context.Context, olderThan time.Duration, now time.Time, connection shared.Connection) ([]cmd.TrashEntry, error) {
+	entries, err := cmd.GetTrashEntries(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []cmd.TrashEntry{}
+	for _, entry := range entries {
+		if olderThan > 0 {
+			t, err := entry.Time()
+			if err != nil || now.Sub(t) < olderThan {
+				continue
+			}
+		}
+
+		if err := cmd.RemoveTrashEntry(ctx, entry, connection); err != nil {
+			return nil, err
+		}
+		results = append(results, entry)
+	}
+
+	return results, nil
+}
+
+// RestoreBranches recreates the branches from their latest backups.
+// If branchNames is empty, all branches deleted in the last run are restored.
+func RestoreBranches(ctx context.Context, branchNames []string, connection shared.Connection) ([]RestoreResult, error) {
+	entries, err := cmd.GetTrashEntries(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []cmd.TrashEntry{}
+	if len(branchNames) == 0 {
+		if len(entries) > 0 {
+			last := entries[len(entries)-1].Timestamp
+			for _, entry := range entries {
+				if entry.Timestamp == last {
+					targets = append(targets, entry)
+				}
+			}
+		}
+	} else {
+		for _, name := range branchNames {
+			found := false
+			for i := len(entries) - 1; i >= 0; i-- {
+				if entries[i].Name == name {
+					targets = append(targets, entries[i])
+					found = true
+					break
+				}
+			}
+			if !found {
+				targets = append(targets, cmd.TrashEntry{Name: name})
+			}
+		}
+	}
+
+	results := []RestoreResult{}
+	for _, entry := range targets {
+		if entry.Ref == "" {
+			results = append(results, RestoreResult{entry.Name, "", cmd.ErrNotFound})
+			continue
+		}
+		results = append(results, RestoreResult{entry.Name, entry.Oid, restoreEntry(ctx, entry, connection)})
+	}
+
+	return results, nil
+}
+
+func restoreEntry(ctx context.Context, entry cmd.TrashEntry, connection shared.Connection) error {
+	if _, err := connection.CreateBranch(ctx, entry.Name, entry.Oid); err != nil {
+		return err
+	}
+
+	configs, _ := connection.GetConfigRegexp(ctx, entry.ConfigPattern())
+	for _, config := range cmd.ToConfigEntries(cmd.SplitLines(configs)) {
+		key := fmt.Sprintf("branch.%s.%s", entry.Name, cmd.ConfigVariable(config.Key))
+		if _, err := connection.AddConfig(ctx, key, config.Value); err != nil {
+			return err
+		}
+	}
+
+	return cmd.RemoveTrashEntry(ctx, entry, connection)
+}
@@ -0,0 +1,99 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/conn"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrCommand = errors.New("failed to run external command")
+
+func Test_RestoreBranchesDeletedInTheLastRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1_issue2", nil, nil).
+		CreateBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetConfigRegexp("trashIssue2", nil, nil).
+		AddConfig(nil, conn.NewConf(&conn.Times{N: 2})).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	actual, _ := RestoreBranches(context.Background(), []string{}, s.Conn)
+
+	assert.Equal(t, []RestoreResult{
+		{Name: "issue2", Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+	}, actual)
+}
+
+func Test_RestoreTheLatestBackupOfTheBranch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1_issue2", nil, nil).
+		CreateBranch(nil, conn.NewConf(&conn.Times{N: 1})).
+		GetConfigRegexp("empty", nil, nil).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	actual, _ := RestoreBranches(context.Background(), []string{"issue1", "issue3"}, s.Conn)
+
+	assert.Equal(t, []RestoreResult{
+		{Name: "issue1", Oid: "b8a2645298053fb62ea03e27feea6c483d3fd27e"},
+		{Name: "issue3", Err: cmd.ErrNotFound},
+	}, actual)
+}
+
+func Test_KeepTheBackupWhenTheBranchAlreadyExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1_issue2", nil, nil).
+		CreateBranch(ErrCommand, conn.NewConf(&conn.Times{N: 1})).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 0}))
+
+	actual, _ := RestoreBranches(context.Background(), []string{"issue2"}, s.Conn)
+
+	assert.Equal(t, []RestoreResult{
+		{Name: "issue2", Oid: "6ebe3d30d23531af56bd23b5a098d3ccae2a534a", Err: ErrCommand},
+	}, actual)
+}
+
+func Test_EmptyTrash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1_issue2", nil, nil).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 3})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 3}))
+
+	actual, _ := EmptyTrash(context.Background(), 0, time.Now(), s.Conn)
+
+	assert.Equal(t, 3, len(actual))
+}
+
+func Test_EmptyOnlyTheTrashOlderThanTheAge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := conn.Setup(ctrl).
+		GetRefs("issue1_issue2", nil, nil).
+		DeleteRef(nil, conn.NewConf(&conn.Times{N: 1})).
+		RemoveConfigSection(nil, conn.NewConf(&conn.Times{N: 1}))
+
+	actual, _ := EmptyTrash(context.Background(), 24*time.Hour, time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC), s.Conn)
+
+	assert.Equal(t, []cmd.TrashEntry{
+		{Ref: "refs/gh-poi/trash/20220101T000000Z/issue1", Timestamp: "20220101T000000Z", Name: "issue1", Oid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+	}, actual)
+}
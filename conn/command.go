@@ -3,18 +3,25 @@ package conn
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/cli/safeexec"
+	"github.com/seachicken/gh-poi/shared"
 )
 
 type (
 	Connection struct {
 		Debug bool
+		// Log records the external commands when it is not nil.
+		Log *CommandLog
 	}
 
 	DebugMask int
@@ -98,33 +105,66 @@ func (conn *Connection) GetLog(ctx context.Context, branchName string) (string,
 	return conn.run(ctx, "git", args, None)
 }
 
-func (conn *Connection) GetAssociatedRefNames(ctx context.Context, oid string) (string, error) {
+// GetBranchRefs lists the local and remote branches with their tips, in the same order as git branch --all.
+func (conn *Connection) GetBranchRefs(ctx context.Context) (string, error) {
 	args := []string{
-		"branch", "--all", "--format=%(refname)",
-		"--contains", oid,
+		"for-each-ref", "--format=%(refname):%(objectname)", "refs/heads/", "refs/remotes/",
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) GetMergeBase(ctx context.Context, oids []string) (string, error) {
+	args := append([]string{
+		"merge-base", "--octopus",
+	}, oids...)
+	return conn.run(ctx, "git", args, None)
+}
+
+// GetCommitGraph lists the commits reachable from the branches and the oids, children first,
+// each followed by its parents. The ancestors of baseOids are left out, but baseOids themselves are not.
+func (conn *Connection) GetCommitGraph(ctx context.Context, oids []string, baseOids []string) (string, error) {
+	args := append([]string{
+		"rev-list", "--topo-order", "--parents", "--branches", "--remotes",
+	}, oids...)
+	if len(baseOids) > 0 {
+		args = append(args, "--not")
+		for _, oid := range baseOids {
+			args = append(args, oid+"^@")
+		}
 	}
 	return conn.run(ctx, "git", args, None)
 }
 
 // https://docs.github.com/en/search-github/searching-on-github/searching-issues-and-pull-requests#search-within-a-users-or-organizations-repositories
+// Each of queryHashes is searched in an aliased search field of the same query, so they are fetched in one round trip.
+// The search continues after the cursor at the same index, or starts from the first page when it is empty.
 func (conn *Connection) GetPullRequests(
 	ctx context.Context,
-	hostname string, orgs string, repos string, queryHashes string) (string, error) {
-	args := []string{
-		"api", "graphql",
-		"--hostname", hostname,
-		"-f", fmt.Sprintf(`query=query {
-  search(type: ISSUE, query: "is:pr %s %s %s", last: 100) {
+	hostname string, orgs string, repos string, queryHashes []string, cursors []string) (string, error) {
+	var searches strings.Builder
+	for i, hashes := range queryHashes {
+		searches.WriteString(fmt.Sprintf(`  %s: search(type: ISSUE, query: "is:pr %s %s %s", first: 100%s) {
     issueCount
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
     edges {
       node {
         ... on PullRequest {
+          id
           number
           url
           state
           isDraft
           headRefName
+          closedAt
           commits(last: 10) {
+            totalCount
+            pageInfo {
+              hasPreviousPage
+              startCursor
+            }
             nodes {
               commit {
                 oid
@@ -136,13 +176,56 @@ func (conn *Connection) GetPullRequests(
       }
     }
   }
-}`,
-			orgs, repos, queryHashes,
-		),
+`,
+			shared.GetSearchAlias(i), orgs, repos, hashes, afterCursor(cursors[i]),
+		))
+	}
+	args := []string{
+		"api", "graphql",
+		"--hostname", hostname,
+		"-f", fmt.Sprintf("query=query {\n%s}", searches.String()),
 	}
 	return conn.run(ctx, "gh", args, None)
 }
 
+// GetPullRequestCommits fetches the commits before the cursor at the same index of each pull request.
+func (conn *Connection) GetPullRequestCommits(ctx context.Context, hostname string, ids []string, cursors []string) (string, error) {
+	var nodes strings.Builder
+	for i, id := range ids {
+		nodes.WriteString(fmt.Sprintf(`  %s: node(id: "%s") {
+    ... on PullRequest {
+      commits(last: 100, before: "%s") {
+        pageInfo {
+          hasPreviousPage
+          startCursor
+        }
+        nodes {
+          commit {
+            oid
+          }
+        }
+      }
+    }
+  }
+`,
+			shared.GetCommitsAlias(i), id, cursors[i],
+		))
+	}
+	args := []string{
+		"api", "graphql",
+		"--hostname", hostname,
+		"-f", fmt.Sprintf("query=query {\n%s}", nodes.String()),
+	}
+	return conn.run(ctx, "gh", args, None)
+}
+
+func afterCursor(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf(`, after: "%s"`, cursor)
+}
+
 func (conn *Connection) GetUncommittedChanges(ctx context.Context) (string, error) {
 	args := []string{
 		"status", "--short",
@@ -150,6 +233,29 @@ func (conn *Connection) GetUncommittedChanges(ctx context.Context) (string, erro
 	return conn.run(ctx, "git", args, None)
 }
 
+// GetWorkingTreeFile reads the file at the path relative to the top of the working tree.
+func (conn *Connection) GetWorkingTreeFile(ctx context.Context, path string) (string, error) {
+	args := []string{
+		"rev-parse", "--show-toplevel",
+	}
+	topLevel, err := conn.run(ctx, "git", args, None)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(filepath.Join(strings.TrimSpace(topLevel), path))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (conn *Connection) GetRefFile(ctx context.Context, ref string, path string) (string, error) {
+	args := []string{
+		"show", fmt.Sprintf("%s:%s", ref, path),
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
 func (conn *Connection) GetConfig(ctx context.Context, key string) (string, error) {
 	args := []string{
 		"config", "--get", key,
@@ -157,6 +263,28 @@ func (conn *Connection) GetConfig(ctx context.Context, key string) (string, erro
 	return conn.run(ctx, "git", args, None)
 }
 
+func (conn *Connection) GetConfigRegexp(ctx context.Context, pattern string) (string, error) {
+	args := []string{
+		"config", "--get-regexp", pattern,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) GetConfigRegexpWithScope(ctx context.Context, pattern string) (string, error) {
+	args := []string{
+		"config", "--show-scope", "--get-regexp", pattern,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+// GetBranchConfig reads the config of all branches at once.
+func (conn *Connection) GetBranchConfig(ctx context.Context) (string, error) {
+	args := []string{
+		"config", "--get-regexp", `^branch\.`,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
 func (conn *Connection) AddConfig(ctx context.Context, key string, value string) (string, error) {
 	args := []string{
 		"config", "--add", key, value,
@@ -171,6 +299,41 @@ func (conn *Connection) RemoveConfig(ctx context.Context, key string) (string, e
 	return conn.run(ctx, "git", args, None)
 }
 
+func (conn *Connection) RemoveConfigValue(ctx context.Context, key string, value string) (string, error) {
+	args := []string{
+		"config", "--unset-all", key, "^" + regexp.QuoteMeta(value) + "$",
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) RemoveConfigSection(ctx context.Context, section string) (string, error) {
+	args := []string{
+		"config", "--remove-section", section,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) GetRefs(ctx context.Context, prefix string) (string, error) {
+	args := []string{
+		"for-each-ref", "--format=%(refname):%(objectname)", prefix,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) UpdateRef(ctx context.Context, ref string, oid string) (string, error) {
+	args := []string{
+		"update-ref", ref, oid,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) DeleteRef(ctx context.Context, ref string) (string, error) {
+	args := []string{
+		"update-ref", "-d", ref,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
 func (conn *Connection) CheckoutBranch(ctx context.Context, branchName string) (string, error) {
 	args := []string{
 		"checkout", "--quiet", branchName,
@@ -178,11 +341,25 @@ func (conn *Connection) CheckoutBranch(ctx context.Context, branchName string) (
 	return conn.run(ctx, "git", args, None)
 }
 
-func (conn *Connection) DeleteBranches(ctx context.Context, branchNames []string) (string, error) {
-	args := append([]string{
-		"branch", "-D"},
-		branchNames...,
-	)
+func (conn *Connection) CreateBranch(ctx context.Context, branchName string, oid string) (string, error) {
+	args := []string{
+		"branch", branchName, oid,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+// DeleteBranch deletes the branch only when its tip is still the oid.
+func (conn *Connection) DeleteBranch(ctx context.Context, branchName string, oid string) (string, error) {
+	args := []string{
+		"update-ref", "-d", "refs/heads/" + branchName, oid,
+	}
+	return conn.run(ctx, "git", args, None)
+}
+
+func (conn *Connection) GetWorktrees(ctx context.Context) (string, error) {
+	args := []string{
+		"worktree", "list", "--porcelain",
+	}
 	return conn.run(ctx, "git", args, None)
 }
 
@@ -196,12 +373,14 @@ func (conn *Connection) PruneRemoteBranches(ctx context.Context, remoteName stri
 func (conn *Connection) run(ctx context.Context, name string, args []string, mask DebugMask) (string, error) {
 	cmdPath, err := safeexec.LookPath(name)
 	if err != nil {
-		return "", err
+		return "", &shared.CommandError{Name: name, Args: redactArgs(args), Kind: shared.CommandNotFound, Err: err}
 	}
 
 	var stdout bytes.Buffer
+	var stderr bytes.Buffer
 	cmd := exec.CommandContext(ctx, cmdPath, args...)
 	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 	if name == "gh" {
 		cmd.Env = append(os.Environ(), "CLICOLOR_FORCE=0")
 	}
@@ -209,19 +388,55 @@ func (conn *Connection) run(ctx context.Context, name string, args []string, mas
 	start := time.Now()
 	err = cmd.Run()
 	duration := time.Since(start)
-	if err != nil {
-		err = fmt.Errorf("failed to run external command: %s, args: %v\n %w", name, args, err)
-		return "", err
+
+	if conn.Log != nil {
+		conn.Log.add(CommandRecord{
+			Time:        start,
+			Tool:        name,
+			Args:        redactArgs(args),
+			Duration:    duration,
+			ExitCode:    exitCode(err),
+			StdoutBytes: stdout.Len(),
+			StderrBytes: stderr.Len(),
+		})
 	}
 
 	if conn.Debug {
-		switch mask {
-		case None:
-			log.Printf("[%v] run %s %v -> %q\n", duration, name, args, stdout.String())
-		case Output:
-			log.Printf("[%v] run %s %v -> *****\n", duration, name, args)
+		output, errOutput := fmt.Sprintf("%q", Redact(stdout.String())), fmt.Sprintf("%q", Redact(stderr.String()))
+		if mask == Output {
+			output, errOutput = redacted, redacted
+		}
+		switch {
+		case err != nil:
+			log.Printf("[%v] run %s %v -> %v, stderr: %s\n", duration, name, redactArgs(args), err, errOutput)
+		case stderr.Len() > 0:
+			log.Printf("[%v] run %s %v -> %s, stderr: %s\n", duration, name, redactArgs(args), output, errOutput)
+		default:
+			log.Printf("[%v] run %s %v -> %s\n", duration, name, redactArgs(args), output)
+		}
+	}
+
+	if err != nil {
+		// Errors are printed, so they must not contain credentials either.
+		return "", &shared.CommandError{
+			Name:   name,
+			Args:   redactArgs(args),
+			Stderr: Redact(stderr.String()),
+			Kind:   classifyError(stderr.String()),
+			Err:    err,
 		}
 	}
 
-	return stdout.String(), err
+	return stdout.String(), nil
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
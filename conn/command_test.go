@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/seachicken/gh-poi/shared"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,23 +53,30 @@ func Test_RepoBasic(t *testing.T) {
 		})
 	})
 
-	t.Run("GetAssociatedRefNames", func(t *testing.T) {
+	t.Run("GetBranchRefs", func(t *testing.T) {
+		actual, _ := conn.GetBranchRefs(context.Background())
+		assert.Equal(t,
+			stub.readFile("git", "refs", "main_issue1"),
+			actual,
+		)
+	})
 
-		t.Run("issue1", func(t *testing.T) {
-			actual, _ := conn.GetAssociatedRefNames(context.Background(), "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0")
-			assert.Equal(t,
-				stub.readFile("git", "abranch", "issue1"),
-				actual,
-			)
+	t.Run("GetMergeBase", func(t *testing.T) {
+		actual, _ := conn.GetMergeBase(context.Background(), []string{
+			"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0", "6ebe3d30d23531af56bd23b5a098d3ccae2a534a",
 		})
+		assert.Equal(t,
+			stub.readFile("git", "mergeBase", "main"),
+			actual,
+		)
+	})
 
-		t.Run("main_issue1", func(t *testing.T) {
-			actual, _ := conn.GetAssociatedRefNames(context.Background(), "6ebe3d30d23531af56bd23b5a098d3ccae2a534a")
-			assert.Equal(t,
-				stub.readFile("git", "abranch", "main_issue1"),
-				actual,
-			)
-		})
+	t.Run("GetCommitGraph", func(t *testing.T) {
+		actual, _ := conn.GetCommitGraph(context.Background(), []string{}, []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"})
+		assert.Equal(t,
+			stub.readFile("git", "graph", "issue1"),
+			actual,
+		)
 	})
 
 	t.Run("GetUncommittedChanges", func(t *testing.T) {
@@ -94,6 +102,42 @@ func Test_RepoBasic(t *testing.T) {
 		conn.RemoveConfig(context.Background(), "branch.issue2.gh-poi-protected")
 	})
 
+	t.Run("GetConfigRegexp", func(t *testing.T) {
+		conn.AddConfig(context.Background(), "branch.issue2.gh-poi-protected", "true")
+		actual, _ := conn.GetConfigRegexp(context.Background(), `^branch\.issue2\.`)
+		assert.Equal(t, "branch.issue2.gh-poi-protected true\n", actual)
+		conn.RemoveConfigSection(context.Background(), "branch.issue2")
+	})
+
+	t.Run("GetBranchConfig", func(t *testing.T) {
+		conn.AddConfig(context.Background(), "branch.issue2.gh-poi-protected", "true")
+		actual, _ := conn.GetBranchConfig(context.Background())
+		assert.Contains(t, actual, "branch.main.merge refs/heads/main\n")
+		assert.Contains(t, actual, "branch.issue2.gh-poi-protected true\n")
+		conn.RemoveConfigSection(context.Background(), "branch.issue2")
+	})
+
+	t.Run("UpdateAndDeleteRef", func(t *testing.T) {
+		conn.UpdateRef(context.Background(), "refs/gh-poi/trash/20220101T000000Z/issue1", "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0")
+		actual, _ := conn.GetRefs(context.Background(), "refs/gh-poi/trash/")
+		assert.Equal(t, "refs/gh-poi/trash/20220101T000000Z/issue1:a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0\n", actual)
+
+		conn.DeleteRef(context.Background(), "refs/gh-poi/trash/20220101T000000Z/issue1")
+		actual, _ = conn.GetRefs(context.Background(), "refs/gh-poi/trash/")
+		assert.Equal(t, "", actual)
+	})
+
+	t.Run("DeleteBranch", func(t *testing.T) {
+		conn.CreateBranch(context.Background(), "issue2", "6ebe3d30d23531af56bd23b5a098d3ccae2a534a")
+
+		_, err := conn.DeleteBranch(context.Background(), "issue2", "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0")
+		assert.NotNil(t, err)
+		assert.Contains(t, err.(*shared.CommandError).Message(), "cannot lock ref 'refs/heads/issue2'")
+
+		_, err = conn.DeleteBranch(context.Background(), "issue2", "6ebe3d30d23531af56bd23b5a098d3ccae2a534a")
+		assert.Nil(t, err)
+	})
+
 	t.Run("AddAndRemoveConfig", func(t *testing.T) {
 		conn.AddConfig(context.Background(), "branch.issue2.gh-poi-protected", "true")
 		conn.RemoveConfig(context.Background(), "branch.issue2.gh-poi-protected")
@@ -0,0 +1,52 @@
+package conn
+
+import (
+	"strings"
+
+	"github.com/seachicken/gh-poi/shared"
+)
+
+var errorPatterns = []struct {
+	kind     shared.CommandErrorKind
+	messages []string
+}{
+	{shared.RateLimited, []string{
+		"rate limit",
+		"http 429",
+	}},
+	{shared.Unauthenticated, []string{
+		"gh auth login",
+		"http 401",
+		"bad credentials",
+		"requires authentication",
+		"authentication failed",
+	}},
+	{shared.RepoNotFound, []string{
+		"could not resolve to a repository",
+		"repository not found",
+		"http 404",
+	}},
+	{shared.NetworkError, []string{
+		"dial tcp",
+		"no such host",
+		"could not resolve host",
+		"connection refused",
+		"connection timed out",
+		"i/o timeout",
+		"network is unreachable",
+		"tls handshake timeout",
+	}},
+}
+
+// classifyError tells the cause of the failure from the messages printed by gh, git or ssh.
+func classifyError(stderr string) shared.CommandErrorKind {
+	stderr = strings.ToLower(stderr)
+	for _, pattern := range errorPatterns {
+		for _, message := range pattern.messages {
+			if strings.Contains(stderr, message) {
+				return pattern.kind
+			}
+		}
+	}
+	return shared.UnknownError
+}
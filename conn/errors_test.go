@@ -0,0 +1,27 @@
+package conn
+
+import (
+	"testing"
+
+	"github.com/seachicken/gh-poi/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClassifyError(t *testing.T) {
+	assert.Equal(t, shared.Unauthenticated,
+		classifyError("To get started with GitHub CLI, please run:  gh auth login\n"))
+	assert.Equal(t, shared.Unauthenticated,
+		classifyError("HTTP 401: Bad credentials (https://api.github.com/graphql)\n"))
+	assert.Equal(t, shared.RepoNotFound,
+		classifyError("gh: Not Found (HTTP 404)\n"))
+	assert.Equal(t, shared.RateLimited,
+		classifyError("GraphQL: API rate limit exceeded for user ID 1.\n"))
+	assert.Equal(t, shared.RateLimited,
+		classifyError("HTTP 403: You have exceeded a secondary rate limit.\n"))
+	assert.Equal(t, shared.NetworkError,
+		classifyError("Post \"https://api.github.com/graphql\": dial tcp: lookup api.github.com: no such host\n"))
+	assert.Equal(t, shared.NetworkError,
+		classifyError("fatal: unable to access 'https://github.com/a/b.git/': Could not resolve host: github.com\n"))
+	assert.Equal(t, shared.UnknownError,
+		classifyError("error: cannot lock ref 'refs/heads/issue1'\n"))
+}
@@ -0,0 +1,89 @@
+package conn
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// CommandRecord is written to the log file as a JSON line for each external command.
+	CommandRecord struct {
+		Time        time.Time     `json:"time"`
+		Tool        string        `json:"tool"`
+		Args        []string      `json:"args"`
+		Duration    time.Duration `json:"-"`
+		DurationMs  float64       `json:"durationMs"`
+		ExitCode    int           `json:"exitCode"`
+		StdoutBytes int           `json:"stdoutBytes"`
+		StderrBytes int           `json:"stderrBytes"`
+	}
+
+	// CommandLog records the external commands run by the Connection.
+	CommandLog struct {
+		mu      sync.Mutex
+		writer  io.Writer
+		records []CommandRecord
+	}
+
+	ToolSummary struct {
+		Tool     string
+		Count    int
+		Duration time.Duration
+	}
+)
+
+// NewCommandLog returns a log that also writes the records to the writer when it is not nil.
+func NewCommandLog(writer io.Writer) *CommandLog {
+	return &CommandLog{writer: writer}
+}
+
+func (l *CommandLog) add(record CommandRecord) {
+	record.DurationMs = float64(record.Duration.Microseconds()) / 1000
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+	if l.writer != nil {
+		// The log is for diagnosis, so failing to write it does not fail the command.
+		json.NewEncoder(l.writer).Encode(record)
+	}
+}
+
+// Summary returns the total time spent in each tool, the slowest first.
+func (l *CommandLog) Summary() []ToolSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	results := []ToolSummary{}
+	for _, record := range l.records {
+		found := false
+		for i := range results {
+			if results[i].Tool == record.Tool {
+				results[i].Count++
+				results[i].Duration += record.Duration
+				found = true
+			}
+		}
+		if !found {
+			results = append(results, ToolSummary{record.Tool, 1, record.Duration})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Duration > results[j].Duration })
+	return results
+}
+
+// Slowest returns up to n records that took the longest.
+func (l *CommandLog) Slowest(n int) []CommandRecord {
+	l.mu.Lock()
+	results := append([]CommandRecord{}, l.records...)
+	l.mu.Unlock()
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Duration > results[j].Duration })
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results
+}
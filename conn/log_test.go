@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommandLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewCommandLog(&buf)
+
+	log.add(CommandRecord{Tool: "git", Args: []string{"remote", "-v"}, Duration: 2 * time.Millisecond, StdoutBytes: 10})
+	log.add(CommandRecord{Tool: "gh", Args: []string{"api", "graphql"}, Duration: 30 * time.Millisecond, ExitCode: 1, StderrBytes: 5})
+	log.add(CommandRecord{Tool: "git", Args: []string{"status", "--short"}, Duration: 3 * time.Millisecond})
+
+	assert.Equal(t, []ToolSummary{
+		{"gh", 1, 30 * time.Millisecond},
+		{"git", 2, 5 * time.Millisecond},
+	}, log.Summary())
+
+	slowest := log.Slowest(2)
+	assert.Equal(t, 2, len(slowest))
+	assert.Equal(t, []string{"api", "graphql"}, slowest[0].Args)
+	assert.Equal(t, []string{"status", "--short"}, slowest[1].Args)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "gh", record["tool"])
+	assert.Equal(t, float64(30), record["durationMs"])
+	assert.Equal(t, float64(1), record["exitCode"])
+	assert.Equal(t, float64(5), record["stderrBytes"])
+}
@@ -1,9 +1,11 @@
 package conn
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/golang/mock/gomock"
 	"github.com/seachicken/gh-poi/mocks"
@@ -33,16 +35,16 @@ type (
 		Filename   string
 	}
 
-	AssociatedBranchNamesStub struct {
-		Oid      string
-		Filename string
-	}
-
 	LogStub struct {
 		BranchName string
 		Filename   string
 	}
 
+	PullRequestPageStub struct {
+		Cursor   string
+		Filename string
+	}
+
 	ConfigStub struct {
 		BranchName string
 		Filename   string
@@ -178,39 +180,89 @@ func (s *Stub) GetLsRemoteHeadOid(stubs []LsRemoteHeadStub, err error, conf *Con
 	return s
 }
 
-func (s *Stub) GetAssociatedRefNames(stubs []AssociatedBranchNamesStub, err error, conf *Conf) *Stub {
+func (s *Stub) GetLog(stubs []LogStub, err error, conf *Conf) *Stub {
 	s.t.Helper()
 	for _, stub := range stubs {
 		configure(
 			s.Conn.EXPECT().
-				GetAssociatedRefNames(gomock.Any(), stub.Oid).
-				Return(s.readFile("git", "abranch", stub.Filename), err),
+				GetLog(gomock.Any(), stub.BranchName).
+				Return(s.readFile("git", "log", stub.Filename), err),
 			conf,
 		)
 	}
 	return s
 }
 
-func (s *Stub) GetLog(stubs []LogStub, err error, conf *Conf) *Stub {
+func (s *Stub) GetBranchRefs(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetBranchRefs(gomock.Any()).
+			Return(s.readFile("git", "refs", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetMergeBase(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetMergeBase(gomock.Any(), gomock.Any()).
+			Return(s.readFile("git", "mergeBase", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetCommitGraph(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetCommitGraph(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(s.readFile("git", "graph", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetPullRequests(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetPullRequests(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(s.readFile("gh", "pr", filename), err),
+		conf,
+	)
+	return s
+}
+
+// GetPullRequestPages returns the page of a single search that continues after the cursor.
+func (s *Stub) GetPullRequestPages(stubs []PullRequestPageStub, err error, conf *Conf) *Stub {
 	s.t.Helper()
 	for _, stub := range stubs {
 		configure(
-			s.Conn.EXPECT().
-				GetLog(gomock.Any(), stub.BranchName).
-				Return(s.readFile("git", "log", stub.Filename), err),
+			s.Conn.
+				EXPECT().
+				GetPullRequests(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), []string{stub.Cursor}).
+				Return(s.readFile("gh", "pr", stub.Filename), err),
 			conf,
 		)
 	}
 	return s
 }
 
-func (s *Stub) GetPullRequests(filename string, err error, conf *Conf) *Stub {
+func (s *Stub) GetPullRequestCommits(filename string, err error, conf *Conf) *Stub {
 	s.t.Helper()
 	configure(
 		s.Conn.
 			EXPECT().
-			GetPullRequests(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-			Return(s.readFile("gh", "pr", filename), err),
+			GetPullRequestCommits(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(s.readFile("gh", "prCommits", filename), err),
 		conf,
 	)
 	return s
@@ -228,6 +280,30 @@ func (s *Stub) GetUncommittedChanges(uncommittedChanges string, err error, conf
 	return s
 }
 
+func (s *Stub) GetWorkingTreeFile(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetWorkingTreeFile(gomock.Any(), ".github/gh-poi.yml").
+			Return(s.readFile("git", "policy", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetRefFile(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetRefFile(gomock.Any(), "origin/main", ".github/gh-poi.yml").
+			Return(s.readFile("git", "policy", filename), err),
+		conf,
+	)
+	return s
+}
+
 func (s *Stub) GetConfig(stubs []ConfigStub, err error, conf *Conf) *Stub {
 	s.t.Helper()
 	for _, stub := range stubs {
@@ -242,6 +318,111 @@ func (s *Stub) GetConfig(stubs []ConfigStub, err error, conf *Conf) *Stub {
 	return s
 }
 
+// GetBranchConfig returns the values of the stubs as git config --get-regexp prints them.
+func (s *Stub) GetBranchConfig(stubs []ConfigStub, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	config := ""
+	for _, stub := range stubs {
+		for _, value := range strings.Split(s.readFile("git", "config", stub.Filename), "\n") {
+			if value != "" {
+				config += fmt.Sprintf("%s %s\n", stub.BranchName, value)
+			}
+		}
+	}
+	configure(
+		s.Conn.
+			EXPECT().
+			GetBranchConfig(gomock.Any()).
+			Return(config, err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetConfigRegexp(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetConfigRegexp(gomock.Any(), gomock.Any()).
+			Return(s.readFile("git", "configRegexp", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetConfigRegexpWithScope(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetConfigRegexpWithScope(gomock.Any(), gomock.Any()).
+			Return(s.readFile("git", "configScope", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) AddConfig(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			AddConfig(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) RemoveConfig(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			RemoveConfig(gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) UpdateRef(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			UpdateRef(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetRefs(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetRefs(gomock.Any(), gomock.Any()).
+			Return(s.readFile("git", "trashRefs", filename), err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) DeleteRef(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			DeleteRef(gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
 func (s *Stub) CheckoutBranch(err error, conf *Conf) *Stub {
 	s.t.Helper()
 	configure(
@@ -254,18 +435,54 @@ func (s *Stub) CheckoutBranch(err error, conf *Conf) *Stub {
 	return s
 }
 
-func (s *Stub) DeleteBranches(err error, conf *Conf) *Stub {
+func (s *Stub) CreateBranch(err error, conf *Conf) *Stub {
 	s.t.Helper()
 	configure(
 		s.Conn.
 			EXPECT().
-			DeleteBranches(gomock.Any(), gomock.Any()).
+			CreateBranch(gomock.Any(), gomock.Any(), gomock.Any()).
 			Return("", err),
 		conf,
 	)
 	return s
 }
 
+func (s *Stub) DeleteBranch(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			DeleteBranch(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) RemoveConfigSection(err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			RemoveConfigSection(gomock.Any(), gomock.Any()).
+			Return("", err),
+		conf,
+	)
+	return s
+}
+
+func (s *Stub) GetWorktrees(filename string, err error, conf *Conf) *Stub {
+	s.t.Helper()
+	configure(
+		s.Conn.
+			EXPECT().
+			GetWorktrees(gomock.Any()).
+			Return(s.readFile("git", "worktree", filename), err),
+		conf,
+	)
+	return s
+}
+
 func configure(call *gomock.Call, conf *Conf) {
 	if conf == nil || conf.Times == nil {
 		call.AnyTimes()
@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/cmd/explain"
+	"github.com/seachicken/gh-poi/cmd/plan"
 	"github.com/seachicken/gh-poi/cmd/protect"
+	"github.com/seachicken/gh-poi/cmd/trash"
 	"github.com/seachicken/gh-poi/conn"
 	"github.com/seachicken/gh-poi/shared"
 )
@@ -22,13 +28,54 @@ var (
 	hiBlack   = color.New(color.FgHiBlack).SprintFunc()
 	green     = color.New(color.FgGreen).SprintFunc()
 	red       = color.New(color.FgRed).SprintFunc()
+	yellow    = color.New(color.FgYellow).SprintFunc()
 )
 
+// Exit codes, documented in README.md.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitAPIError     = 3
+	exitDeleteFailed = 4
+	exitDeletable    = 10
+)
+
+// commandLog records the external commands for --debug and --log-file.
+var commandLog *conn.CommandLog
+
+// runOptions are the flags of the root command.
+type runOptions struct {
+	dryRun     bool
+	jsonOutput bool
+	assumeYes  bool
+	debug      bool
+	remote     string
+	// includeClosed is nil unless --include-closed is given on the command line.
+	includeClosed *bool
+	prune         bool
+	// planPath is where the dry run writes the plan to apply later.
+	planPath string
+	check    bool
+	// jobs is the number of branches analyzed at once.
+	jobs int
+}
+
 func main() {
-	var dryRun bool
-	var debug bool
-	flag.BoolVar(&dryRun, "dry-run", false, "Show branches to delete")
-	flag.BoolVar(&debug, "debug", false, "Enable debug logs")
+	var opts runOptions
+	var includeClosed bool
+	var logFilePath string
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "Show branches to delete")
+	flag.BoolVar(&opts.jsonOutput, "json", false, "Output results in JSON format")
+	flag.BoolVar(&opts.assumeYes, "yes", false, "Delete branches without selecting them interactively")
+	flag.BoolVar(&opts.debug, "debug", false, "Enable debug logs")
+	flag.StringVar(&logFilePath, "log-file", "", "Write the external commands run to the file as JSON lines")
+	flag.StringVar(&opts.remote, "remote", "", "Use the remote instead of origin")
+	flag.BoolVar(&includeClosed, "include-closed", false, "Delete branches whose pull requests were closed without merging")
+	flag.BoolVar(&opts.prune, "prune", true, "Prune remote-tracking branches after deleting")
+	flag.StringVar(&opts.planPath, "plan", "", "Write the branches to delete to the file without deleting them (see apply)")
+	flag.BoolVar(&opts.check, "check", false, "Only print the number of branches to delete, exiting with 10 if there are any")
+	flag.IntVar(&opts.jobs, "jobs", cmd.DefaultJobs, "Analyze up to `N` branches at once")
 	flag.Usage = func() {
 		fmt.Fprintf(color.Output, "%s\n\n", white("Delete the merged local branches."))
 		fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
@@ -37,6 +84,12 @@ func main() {
 		fmt.Fprintf(color.Output, "%s\n", white(`
   protect:   Protect local branches from deletion
   unprotect: Unprotect local branches
+  protected: List protected local branches
+  explain:   Explain why a local branch is deleted or not
+  restore:   Restore deleted local branches
+  trash:     Manage backups of deleted local branches
+  apply:     Delete the branches in a plan written by --plan
+  config:    List the defaults of the flags read from git config
   `))
 		fmt.Fprintf(color.Output, "%s\n", whiteBold("FLAGS"))
 		flag.PrintDefaults()
@@ -45,99 +98,474 @@ func main() {
 	flag.Parse()
 	args := flag.Args()
 
+	// The log is opened first so the git config call of the settings is recorded too.
+	logFile, err := openCommandLog(logFilePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	settings, err := cmd.GetSettings(context.Background(), &conn.Connection{Debug: opts.debug, Log: commandLog})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: the defaults in the gh-poi.* git config are ignored: %v\n", err)
+	}
+	if err := applySettings(flag.CommandLine, settings); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	if isFlagSet(flag.CommandLine, "include-closed") {
+		opts.includeClosed = &includeClosed
+	}
+	debug := opts.debug
+
+	exit := func(code int) {
+		if debug {
+			printCommandSummary(commandLog)
+		}
+		if logFile != nil {
+			logFile.Close()
+		}
+		os.Exit(code)
+	}
+
 	if len(args) == 0 {
-		runMain(dryRun, debug)
+		if opts.jobs < 1 {
+			fmt.Fprintln(os.Stderr, "--jobs must be at least 1")
+			exit(exitUsage)
+		}
+		if opts.check {
+			exit(runCheck(opts))
+		}
+		exit(runMain(opts))
 	} else {
 		subcmd, args := args[0], args[1:]
 		switch subcmd {
 		case "protect":
+			var patterns patternsFlag
+			var untilDate string
+			var forDuration string
+			var note string
 			protectCmd := flag.NewFlagSet("protect", flag.ExitOnError)
+			protectCmd.Var(&patterns.globs, "pattern", "Protect branches matching the glob pattern (e.g. 'release/*')")
+			protectCmd.Var(&patterns.regexes, "regex", "Protect branches matching the regular expression")
+			protectCmd.StringVar(&untilDate, "until", "", "Protect the branches until the date (e.g. 2026-12-01)")
+			protectCmd.StringVar(&forDuration, "for", "", "Protect the branches for the duration (e.g. 14d)")
+			protectCmd.StringVar(&note, "m", "", "Record a note explaining why the branches are protected")
+			protectCmd.StringVar(&note, "note", "", "Same as -m")
 			protectCmd.Usage = func() {
 				fmt.Fprintf(color.Output, "%s\n\n", white("Protect local branches from deletion."))
 				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
-				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi protect <branchname>..."))
+				fmt.Fprintf(color.Output, "  %s\n", white("gh poi protect [--pattern <glob>] [--regex <regex>] [--until <date> | --for <duration>] [-m <note>] [<branchname>...]"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("Protects the current branch when no branch name is given, or when the name is \".\"."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("FLAGS"))
+				protectCmd.PrintDefaults()
+				fmt.Println()
 			}
 			protectCmd.Parse(args)
 
-			runProtect(args, debug)
+			branchNames := protectCmd.Args()
+			if len(branchNames) == 0 && len(patterns.values()) == 0 {
+				branchNames = []string{protect.CurrentBranch}
+			}
+
+			until, err := parseUntil(untilDate, forDuration, time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitUsage)
+			}
+			// Patterns are stored without an expiry.
+			if len(patterns.values()) > 0 && !until.IsZero() {
+				fmt.Fprintln(os.Stderr, "--until and --for cannot be used with --pattern or --regex")
+				exit(exitUsage)
+			}
+
+			if strings.ContainsAny(note, "\r\n") {
+				fmt.Fprintln(os.Stderr, "the note must be a single line")
+				exit(exitUsage)
+			}
+			if len(patterns.values()) > 0 && note != "" {
+				fmt.Fprintln(os.Stderr, "-m cannot be used with --pattern or --regex")
+				exit(exitUsage)
+			}
+
+			exit(runProtect(branchNames, patterns.values(), until, note, debug))
 		case "unprotect":
+			var patterns patternsFlag
+			var all bool
+			var stale bool
 			unprotectCmd := flag.NewFlagSet("unprotect", flag.ExitOnError)
+			unprotectCmd.Var(&patterns.globs, "pattern", "Remove the glob pattern")
+			unprotectCmd.Var(&patterns.regexes, "regex", "Remove the regular expression")
+			unprotectCmd.BoolVar(&all, "all", false, "Unprotect all branches")
+			unprotectCmd.BoolVar(&stale, "stale", false, "Unprotect branches that no longer exist locally")
 			unprotectCmd.Usage = func() {
 				fmt.Fprintf(color.Output, "%s\n\n", white("Unprotect local branches."))
 				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
-				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi unprotect <branchname>..."))
+				fmt.Fprintf(color.Output, "  %s\n", white("gh poi unprotect [--pattern <glob>] [--regex <regex>] [<branchname>...]"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi unprotect [--all | --stale]"))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("FLAGS"))
+				unprotectCmd.PrintDefaults()
+				fmt.Println()
 			}
 			unprotectCmd.Parse(args)
 
-			runUnprotect(args, debug)
+			if all || stale {
+				exit(runUnprotectAll(stale && !all, debug))
+			} else {
+				branchNames := unprotectCmd.Args()
+				if len(branchNames) == 0 && len(patterns.values()) == 0 {
+					branchNames = []string{protect.CurrentBranch}
+				}
+
+				exit(runUnprotect(branchNames, patterns.values(), debug))
+			}
+		case "protected":
+			protectedCmd := flag.NewFlagSet("protected", flag.ExitOnError)
+			protectedCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("List protected local branches."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi protected"))
+			}
+			protectedCmd.Parse(args)
+
+			exit(runProtected(debug))
+		case "explain":
+			explainCmd := flag.NewFlagSet("explain", flag.ExitOnError)
+			explainCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("Explain why a local branch is deleted or not."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi explain <branchname>"))
+			}
+			explainCmd.Parse(args)
+			if explainCmd.NArg() != 1 {
+				explainCmd.Usage()
+				exit(exitUsage)
+			}
+
+			exit(runExplain(explainCmd.Arg(0), opts))
+		case "restore":
+			var last bool
+			restoreCmd := flag.NewFlagSet("restore", flag.ExitOnError)
+			restoreCmd.BoolVar(&last, "last", false, "Restore all branches deleted in the last run")
+			restoreCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("Restore deleted local branches."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi restore [<branchname>... | --last]"))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("FLAGS"))
+				restoreCmd.PrintDefaults()
+				fmt.Println()
+			}
+			restoreCmd.Parse(args)
+			if last == (restoreCmd.NArg() > 0) {
+				restoreCmd.Usage()
+				exit(exitUsage)
+			}
+
+			exit(runRestore(restoreCmd.Args(), debug))
+		case "trash":
+			var olderThan string
+			trashCmd := flag.NewFlagSet("trash", flag.ExitOnError)
+			trashCmd.StringVar(&olderThan, "older-than", "", "Only empty backups older than the duration (e.g. 30d)")
+			trashCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("Manage backups of deleted local branches."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n", white("gh poi trash list"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi trash empty [--older-than <duration>]"))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("FLAGS"))
+				trashCmd.PrintDefaults()
+				fmt.Println()
+			}
+			if len(args) == 0 {
+				trashCmd.Usage()
+				exit(exitUsage)
+			}
+			trashCmd.Parse(args[1:])
+
+			switch args[0] {
+			case "list":
+				exit(runTrashList(debug))
+			case "empty":
+				exit(runTrashEmpty(olderThan, debug))
+			default:
+				trashCmd.Usage()
+				exit(exitUsage)
+			}
+		case "apply":
+			applyCmd := flag.NewFlagSet("apply", flag.ExitOnError)
+			applyCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("Delete the branches in a plan written by gh poi --plan."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n", white("gh poi apply <plan.json>"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("Branches whose tips have moved since planning are not deleted."))
+			}
+			applyCmd.Parse(args)
+			if applyCmd.NArg() != 1 {
+				applyCmd.Usage()
+				exit(exitUsage)
+			}
+
+			exit(runApply(applyCmd.Arg(0), opts))
+		case "config":
+			configCmd := flag.NewFlagSet("config", flag.ExitOnError)
+			configCmd.Usage = func() {
+				fmt.Fprintf(color.Output, "%s\n\n", white("List the defaults of the flags read from the gh-poi.* git config."))
+				fmt.Fprintf(color.Output, "%s\n", whiteBold("USAGE"))
+				fmt.Fprintf(color.Output, "  %s\n\n", white("gh poi config list"))
+			}
+			configCmd.Parse(args)
+			if configCmd.NArg() != 1 || configCmd.Arg(0) != "list" {
+				configCmd.Usage()
+				exit(exitUsage)
+			}
+
+			printSettings(settings)
+			exit(exitOK)
 		default:
 			fmt.Fprintf(os.Stderr, "unknown command %q for poi\n", subcmd)
+			exit(exitUsage)
 		}
 	}
 }
 
-func runMain(dryRun bool, debug bool) {
+func newConnection(debug bool) *conn.Connection {
+	return &conn.Connection{Debug: debug, Log: commandLog}
+}
+
+// openCommandLog starts recording the external commands. The records are
+// appended to the file at the path when it is given. It is called before
+// the settings are read, so the records are kept even without --debug in
+// case gh-poi.debug turns it on.
+func openCommandLog(path string) (*os.File, error) {
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		commandLog = conn.NewCommandLog(f)
+		return f, nil
+	}
+	commandLog = conn.NewCommandLog(nil)
+	return nil, nil
+}
+
+// printCommandSummary prints the time spent in each tool and the slowest calls.
+func printCommandSummary(log *conn.CommandLog) {
+	fmt.Fprintf(os.Stderr, "\n%s\n", whiteBold("Time spent in external commands"))
+	for _, summary := range log.Summary() {
+		fmt.Fprintf(os.Stderr, "  %-4s %5d %-5s %10s\n",
+			summary.Tool, summary.Count, pluralize(summary.Count, "call"), summary.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", whiteBold("Slowest calls"))
+	for _, record := range log.Slowest(5) {
+		fmt.Fprintf(os.Stderr, "  %10s  %s\n",
+			record.Duration.Round(time.Millisecond), truncate(strings.Join(strings.Fields(record.Tool+" "+strings.Join(record.Args, " ")), " "), 80))
+	}
+}
+
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// applySettings sets the flags not given on the command line to their defaults in git config.
+func applySettings(flags *flag.FlagSet, settings []cmd.Setting) error {
+	for _, setting := range settings {
+		if setting.Source == cmd.DefaultSource || isFlagSet(flags, setting.Flag) {
+			continue
+		}
+		if err := flags.Set(setting.Flag, setting.Value); err != nil {
+			return fmt.Errorf("invalid %s in %s git config: %s", setting.Key, setting.Source, setting.Value)
+		}
+	}
+	return nil
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	found := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+func printSettings(settings []cmd.Setting) {
+	for _, setting := range settings {
+		value := setting.Value
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(color.Output, "%s %s %s\n",
+			white(fmt.Sprintf("%-20s", setting.Key)), fmt.Sprintf("%-8s", value), hiBlack(setting.Source))
+	}
+}
+
+func runMain(opts runOptions) int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	if dryRun {
+	dryRun, jsonOutput, debug := opts.dryRun || opts.planPath != "", opts.jsonOutput, opts.debug
+
+	if dryRun && !jsonOutput {
 		fmt.Fprintf(color.Output, "%s\n", whiteBold("== DRY RUN =="))
 	}
 
-	connection := &conn.Connection{Debug: debug}
+	connection := newConnection(debug)
 	sp := spinner.New(spinner.CharSets[14], 40*time.Millisecond)
 	defer sp.Stop()
 
 	fetchingMsg := " Fetching pull requests..."
 	sp.Suffix = fetchingMsg
-	if !debug {
+	if !debug && !jsonOutput {
 		sp.Start()
 	}
 	var fetchingErr error
 
-	remote, err := cmd.GetRemote(ctx, connection)
+	remote, err := cmd.GetRemoteByName(ctx, opts.remote, connection)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return
+		if err == cmd.ErrNotFound && opts.remote != "" {
+			err = fmt.Errorf("remote %q not found", opts.remote)
+		}
+		return printError(err, jsonOutput)
 	}
 
-	branches, fetchingErr := cmd.GetBranches(ctx, remote, connection, dryRun)
+	// Do not switch branches before the user selects which ones to delete.
+	interactive := !dryRun && !jsonOutput && !opts.assumeYes && isInteractive()
+	branches, fetchingErr := cmd.GetBranches(ctx, remote, connection, cmd.Options{
+		DryRun:        dryRun || interactive,
+		IncludeClosed: opts.includeClosed,
+		Jobs:          opts.jobs,
+	})
 
 	sp.Stop()
 
 	if fetchingErr == nil {
-		fmt.Fprintf(color.Output, "%s%s\n", green("✔"), fetchingMsg)
+		if !jsonOutput {
+			fmt.Fprintf(color.Output, "%s%s\n", green("✔"), fetchingMsg)
+		}
 	} else {
-		fmt.Fprintf(color.Output, "%s%s\n", red("✕"), fetchingMsg)
-		fmt.Fprintln(os.Stderr, fetchingErr)
-		return
+		if !jsonOutput {
+			fmt.Fprintf(color.Output, "%s%s\n", red("✕"), fetchingMsg)
+		}
+		return printError(fetchingErr, jsonOutput)
+	}
+
+	if opts.planPath != "" {
+		if err := writePlan(opts.planPath, plan.NewPlan(branches, time.Now())); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !jsonOutput {
+			fmt.Fprintf(color.Output, "%s Wrote the plan to %s\n", green("✔"), opts.planPath)
+		}
+	}
+
+	if interactive {
+		selectedNames, ok := promptBranches(os.Stdin, branches)
+		if !ok {
+			fmt.Fprintf(color.Output, "%s\n", hiBlack("Canceled"))
+			return exitOK
+		}
+		branches = cmd.SelectBranches(branches, selectedNames)
 	}
 
 	deletingMsg := " Deleting branches..."
 	var deletingErr error
 
 	if dryRun {
-		fmt.Fprintf(color.Output, "%s%s\n", hiBlack("-"), deletingMsg)
+		if !jsonOutput {
+			fmt.Fprintf(color.Output, "%s%s\n", hiBlack("-"), deletingMsg)
+		}
 	} else {
 		sp.Suffix = deletingMsg
-		if !debug {
+		if !debug && !jsonOutput {
 			sp.Restart()
 		}
 
 		branches, deletingErr = cmd.DeleteBranches(ctx, branches, connection)
-		connection.PruneRemoteBranches(ctx, remote.Name)
+		if opts.prune {
+			connection.PruneRemoteBranches(ctx, remote.Name)
+		}
 
 		sp.Stop()
 
 		if deletingErr == nil {
-			fmt.Fprintf(color.Output, "%s%s\n", green("✔"), deletingMsg)
+			if !jsonOutput {
+				fmt.Fprintf(color.Output, "%s%s\n", green("✔"), deletingMsg)
+			}
 		} else {
-			fmt.Fprintf(color.Output, "%s%s\n", red("✕"), deletingMsg)
-			fmt.Fprintln(os.Stderr, deletingErr)
-			return
+			if !jsonOutput {
+				fmt.Fprintf(color.Output, "%s%s\n", red("✕"), deletingMsg)
+			}
+			return printError(deletingErr, jsonOutput)
 		}
 	}
 
+	printResult(branches, dryRun, jsonOutput)
+	if cmd.HasDeleteFailure(branches) {
+		return exitDeleteFailed
+	}
+	return exitOK
+}
+
+// runCheck prints a summary of the analysis without switching branches or deleting them.
+func runCheck(opts runOptions) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(opts.debug)
+
+	remote, err := cmd.GetRemoteByName(ctx, opts.remote, connection)
+	if err != nil {
+		if err == cmd.ErrNotFound && opts.remote != "" {
+			err = fmt.Errorf("remote %q not found", opts.remote)
+		}
+		return printError(err, false)
+	}
+
+	branches, err := cmd.GetBranches(ctx, remote, connection, cmd.Options{
+		DryRun:        true,
+		IncludeClosed: opts.includeClosed,
+		Jobs:          opts.jobs,
+	})
+	if err != nil {
+		return printError(err, false)
+	}
+
+	for _, branch := range branches {
+		for _, warning := range branch.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s: %s\n", branch.Name, warning)
+		}
+	}
+	n := len(getBranches(branches, []shared.BranchState{shared.Deletable}))
+	fmt.Println(checkSummary(n))
+	if n > 0 {
+		return exitDeletable
+	}
+	return exitOK
+}
+
+func checkSummary(n int) string {
+	switch n {
+	case 0:
+		return "No branches can be deleted"
+	case 1:
+		return "1 branch can be deleted"
+	default:
+		return fmt.Sprintf("%d branches can be deleted", n)
+	}
+}
+
+func printResult(branches []shared.Branch, dryRun bool, jsonOutput bool) {
+	if jsonOutput {
+		printJson(shared.NewReport(branches, dryRun))
+		return
+	}
+
 	fmt.Println()
 
 	var deletedStates []shared.BranchState
@@ -157,31 +585,430 @@ func runMain(dryRun bool, debug bool) {
 	fmt.Fprintf(color.Output, "%s\n", whiteBold("Branches not deleted"))
 	printBranches(getBranches(branches, notDeletedStates))
 	fmt.Println()
+
+	if failed := getBranches(branches, []shared.BranchState{shared.DeleteFailed}); len(failed) > 0 {
+		fmt.Fprintf(color.Output, "%s\n", whiteBold("Branches failed to delete"))
+		printBranches(failed)
+		fmt.Println()
+	}
+}
+
+func writePlan(path string, p plan.Plan) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func runApply(path string, opts runOptions) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	p, err := plan.Parse(b)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	connection := newConnection(opts.debug)
+	branches, err := plan.ApplyPlan(ctx, p, connection)
+	if err != nil {
+		return printError(err, opts.jsonOutput)
+	}
+	if opts.prune {
+		if remote, err := cmd.GetRemoteByName(ctx, opts.remote, connection); err == nil {
+			connection.PruneRemoteBranches(ctx, remote.Name)
+		}
+	}
+
+	printResult(branches, false, opts.jsonOutput)
+	if cmd.HasDeleteFailure(branches) || cmd.HasRefusedBranch(branches) {
+		return exitDeleteFailed
+	}
+	return exitOK
 }
 
-func runProtect(branchNames []string, debug bool) {
+// parseUntil returns the expiry given by --until or --for, or the zero time
+// when neither is set.
+func parseUntil(untilDate string, forDuration string, now time.Time) (time.Time, error) {
+	switch {
+	case untilDate != "" && forDuration != "":
+		return time.Time{}, fmt.Errorf("--until and --for cannot be used together")
+	case untilDate != "":
+		if until, err := time.Parse(time.RFC3339, untilDate); err == nil {
+			return until, nil
+		}
+		until, err := time.ParseInLocation("2006-01-02", untilDate, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --until %q: use YYYY-MM-DD or RFC 3339", untilDate)
+		}
+		return until, nil
+	case forDuration != "":
+		duration, err := cmd.ParseDuration(forDuration)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --for %q: %w", forDuration, err)
+		}
+		return now.Add(duration), nil
+	}
+	return time.Time{}, nil
+}
+
+func runProtect(branchNames []string, patterns []string, until time.Time, note string, debug bool) int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	connection := &conn.Connection{Debug: debug}
+	connection := newConnection(debug)
 
-	err := protect.ProtectBranches(ctx, branchNames, connection)
+	err := protect.ProtectPatterns(ctx, patterns, connection)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return
+		return exitError
 	}
+
+	results, err := protect.ProtectBranches(ctx, branchNames, until, note, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	printProtectResults(results)
+	if protect.HasFailure(results) {
+		return exitError
+	}
+	return exitOK
 }
 
-func runUnprotect(branchNames []string, debug bool) {
+func runUnprotect(branchNames []string, patterns []string, debug bool) int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	connection := &conn.Connection{Debug: debug}
+	connection := newConnection(debug)
 
-	err := protect.UnprotectBranches(ctx, branchNames, connection)
+	err := protect.UnprotectPatterns(ctx, patterns, connection)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		return
+		return exitError
+	}
+
+	results, err := protect.UnprotectBranches(ctx, branchNames, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	printProtectResults(results)
+	if protect.HasFailure(results) {
+		return exitError
+	}
+	return exitOK
+}
+
+func printProtectResults(results []protect.Result) {
+	for _, result := range results {
+		switch result.State {
+		case protect.Protected, protect.Unprotected:
+			fmt.Fprintf(color.Output, "%s %s %s\n", green("✔"), white(result.Name), hiBlack("["+result.State.String()+"]"))
+		case protect.NotFound:
+			fmt.Fprintf(color.Output, "%s %s %s\n", red("✕"), white(result.Name), hiBlack("[branch "+result.State.String()+"]"))
+		default:
+			fmt.Fprintf(color.Output, "%s %s %s\n", hiBlack("-"), white(result.Name), hiBlack("["+result.String()+"]"))
+		}
+	}
+}
+
+func runUnprotectAll(staleOnly bool, debug bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(debug)
+
+	branchNames, err := protect.UnprotectAllBranches(ctx, staleOnly, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	for _, name := range branchNames {
+		fmt.Fprintf(color.Output, "%s Unprotected %s\n", green("✔"), white(name))
+	}
+	if len(branchNames) == 0 {
+		fmt.Fprintf(color.Output, "%s\n", hiBlack("There are no branches to unprotect"))
+	}
+	return exitOK
+}
+
+func runProtected(debug bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(debug)
+
+	branches, err := protect.GetProtectedBranches(ctx, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	patterns, err := protect.GetProtectPatterns(ctx, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	fmt.Fprintf(color.Output, "%s\n", whiteBold("Protected branches"))
+	if len(branches) == 0 {
+		fmt.Fprintf(color.Output, "%s\n", hiBlack("  There are no protected branches"))
+	}
+	now := time.Now()
+	for _, branch := range branches {
+		tags := []string{}
+		if branch.IsStale {
+			tags = append(tags, "stale")
+		}
+		if !branch.Until.IsZero() {
+			if now.Before(branch.Until) {
+				tags = append(tags, "until "+formatDate(branch.Until))
+			} else {
+				tags = append(tags, "protection expired")
+			}
+		}
+		if len(tags) > 0 {
+			fmt.Fprintf(color.Output, "  %s %s\n", white(branch.Name), hiBlack("["+strings.Join(tags, ", ")+"]"))
+		} else {
+			fmt.Fprintf(color.Output, "  %s\n", white(branch.Name))
+		}
+		if branch.Note != "" || branch.Author != "" || !branch.At.IsZero() {
+			fmt.Fprintf(color.Output, "    %s\n", hiBlack(formatProtection(branch.Note, branch.Author, branch.At)))
+		}
+	}
+	fmt.Println()
+
+	fmt.Fprintf(color.Output, "%s\n", whiteBold("Protect patterns"))
+	if len(patterns) == 0 {
+		fmt.Fprintf(color.Output, "%s\n", hiBlack("  There are no patterns"))
+	}
+	for _, pattern := range patterns {
+		fmt.Fprintf(color.Output, "  %s\n", white(pattern))
+	}
+	fmt.Println()
+	return exitOK
+}
+
+func runRestore(branchNames []string, debug bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(debug)
+
+	results, err := trash.RestoreBranches(ctx, branchNames, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	code := exitOK
+	if len(results) == 0 {
+		fmt.Fprintf(color.Output, "%s\n", hiBlack("There are no branches to restore"))
+	}
+	for _, result := range results {
+		if result.Err == nil {
+			fmt.Fprintf(color.Output, "%s Restored %s %s\n", green("✔"), white(result.Name), hiBlack(result.Oid))
+		} else if result.Err == cmd.ErrNotFound {
+			fmt.Fprintf(color.Output, "%s %s %s\n", red("✕"), white(result.Name), hiBlack("[no backup found]"))
+			code = exitError
+		} else {
+			fmt.Fprintf(color.Output, "%s %s\n", red("✕"), white(result.Name))
+			fmt.Fprintln(os.Stderr, result.Err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+func runTrashList(debug bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(debug)
+
+	entries, err := trash.ListTrash(ctx, connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintf(color.Output, "%s\n", hiBlack("There are no backups"))
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(color.Output, "%s  %s %s\n", hiBlack(entry.Timestamp), white(entry.Name), hiBlack(entry.Oid))
+	}
+	return exitOK
+}
+
+func runTrashEmpty(olderThan string, debug bool) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var age time.Duration
+	if olderThan != "" {
+		var err error
+		age, err = cmd.ParseDuration(olderThan)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	connection := newConnection(debug)
+
+	entries, err := trash.EmptyTrash(ctx, age, time.Now(), connection)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	fmt.Fprintf(color.Output, "%s Removed %d %s\n", green("✔"), len(entries), pluralize(len(entries), "backup"))
+	return exitOK
+}
+
+func runExplain(branchName string, opts runOptions) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	connection := newConnection(opts.debug)
+
+	remote, err := cmd.GetRemoteByName(ctx, opts.remote, connection)
+	if err != nil {
+		if err == cmd.ErrNotFound && opts.remote != "" {
+			err = fmt.Errorf("remote %q not found", opts.remote)
+		}
+		return printError(err, false)
+	}
+
+	branch, err := explain.ExplainBranch(ctx, remote, branchName, connection, cmd.Options{
+		IncludeClosed: opts.includeClosed,
+		Jobs:          opts.jobs,
+	})
+	if err == cmd.ErrNotFound {
+		fmt.Fprintf(os.Stderr, "branch %q not found\n", branchName)
+		return exitError
+	} else if err != nil {
+		return printError(err, false)
+	}
+
+	printBranches([]shared.Branch{branch})
+	fmt.Println()
+	steps := branch.Trace
+	if branch.State == shared.NotDeletable {
+		steps = append(steps, "not deletable: "+getReason(branch))
+	}
+	for i, step := range steps {
+		// The steps may contain remote URLs with credentials.
+		fmt.Fprintf(color.Output, "  %s %s\n", hiBlack(fmt.Sprintf("%2d.", i+1)), white(conn.Redact(step)))
+	}
+	fmt.Println()
+	return exitOK
+}
+
+// printError prints the error with a hint to resolve it, and also outputs it
+// as JSON in the JSON output mode. It returns the exit code for the error.
+func printError(err error, jsonOutput bool) int {
+	message, hint := describeError(err)
+	fmt.Fprintln(os.Stderr, message)
+	if hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	if jsonOutput {
+		printJson(shared.NewErrorReport(err, message, hint))
+	}
+	return errorExitCode(err)
+}
+
+// describeError turns the errors of external commands into actionable messages.
+func describeError(err error) (string, string) {
+	var commandErr *shared.CommandError
+	if !errors.As(err, &commandErr) {
+		return err.Error(), ""
+	}
+
+	hostname := commandErr.Hostname()
+	if hostname == "" {
+		hostname = "github.com"
+	}
+	switch commandErr.Kind {
+	case shared.CommandNotFound:
+		if commandErr.Name == "gh" {
+			return "gh is not installed", "Install it from https://cli.github.com"
+		}
+		return fmt.Sprintf("%s is not installed", commandErr.Name), ""
+	case shared.Unauthenticated:
+		return fmt.Sprintf("not logged in to %s", hostname),
+			fmt.Sprintf("Run `gh auth login --hostname %s`", hostname)
+	case shared.RepoNotFound:
+		return fmt.Sprintf("repository not found on %s", hostname),
+			fmt.Sprintf("Check the remote URL, and that the account in `gh auth status --hostname %s` can access the repository", hostname)
+	case shared.RateLimited:
+		return fmt.Sprintf("API rate limit exceeded on %s", hostname), "Wait a few minutes and try again"
+	case shared.NetworkError:
+		if commandErr.Name == "gh" {
+			return fmt.Sprintf("could not connect to %s", hostname), "Check your network connection and proxy settings"
+		}
+		return "could not connect to the remote", "Check your network connection and proxy settings"
+	default:
+		return err.Error(), ""
+	}
+}
+
+// errorExitCode returns exitAPIError when gh failed to call the GitHub API.
+func errorExitCode(err error) int {
+	var commandErr *shared.CommandError
+	if !errors.As(err, &commandErr) || commandErr.Kind == shared.CommandNotFound {
+		return exitError
+	}
+	// git failures to reach the remote are not GitHub API errors, whatever their kind.
+	if commandErr.Name == "gh" {
+		return exitAPIError
+	}
+	return exitError
+}
+
+type (
+	stringsFlag []string
+
+	patternsFlag struct {
+		globs   stringsFlag
+		regexes stringsFlag
+	}
+)
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func (p patternsFlag) values() []string {
+	results := append([]string{}, p.globs...)
+	for _, regex := range p.regexes {
+		results = append(results, cmd.RegexPatternPrefix+regex)
+	}
+	return results
+}
+
+func printJson(report interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
 }
 
@@ -197,34 +1024,146 @@ func printBranches(branches []shared.Branch) {
 		} else {
 			fmt.Fprintf(color.Output, "  %s", white(branch.Name))
 		}
-		reason := ""
-		if branch.IsProtected {
-			reason = "protected"
+		reason := getReason(branch)
+		if branch.ProtectionExpired {
+			reason = strings.TrimPrefix(reason+", protection expired", ", ")
 		}
 		if reason == "" {
 			fmt.Fprintln(color.Output, "")
 		} else {
 			fmt.Fprintf(color.Output, " %s\n", hiBlack("["+reason+"]"))
 		}
+		for _, hint := range branch.DeleteHints {
+			fmt.Fprintf(color.Output, "    %s\n", hiBlack("hint: "+hint))
+		}
+		for _, warning := range branch.Warnings {
+			fmt.Fprintf(color.Output, "    %s\n", yellow("warning: "+warning))
+		}
 
-		for i, pr := range branch.PullRequests {
-			number := fmt.Sprintf("#%v", pr.Number)
-			issueNoColor := getIssueNoColor(pr.State, pr.IsDraft)
-			var line string
-			if i == len(branch.PullRequests)-1 {
-				line = "└─"
-			} else {
-				line = "├─"
-			}
+		printPullRequests(branch, "    ")
+	}
+}
+
+// formatProtection formats a protection note like
+// "waiting on security review (octocat, 2026-10-01)".
+func formatProtection(note string, author string, at time.Time) string {
+	details := []string{}
+	if author != "" {
+		details = append(details, author)
+	}
+	if !at.IsZero() {
+		details = append(details, formatDate(at))
+	}
+	if len(details) == 0 {
+		return note
+	}
+	return strings.TrimPrefix(note+" ("+strings.Join(details, ", ")+")", " ")
+}
+
+func formatDate(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
+
+func printPullRequests(branch shared.Branch, indent string) {
+	for i, pr := range branch.PullRequests {
+		number := fmt.Sprintf("#%v", pr.Number)
+		issueNoColor := getIssueNoColor(pr.State, pr.IsDraft)
+		var line string
+		if i == len(branch.PullRequests)-1 {
+			line = "└─"
+		} else {
+			line = "├─"
+		}
+
+		fmt.Fprintf(color.Output, "%s%s %s  %s %s\n",
+			indent,
+			line,
+			color.New(issueNoColor).SprintFunc()(number),
+			white(pr.Url),
+			hiBlack(pr.Author),
+		)
+	}
+}
 
-			fmt.Fprintf(color.Output, "    %s %s  %s %s\n",
-				line,
-				color.New(issueNoColor).SprintFunc()(number),
-				white(pr.Url),
-				hiBlack(pr.Author),
-			)
+func getReason(branch shared.Branch) string {
+	if branch.State == shared.DeleteFailed {
+		return branch.DeleteError
+	}
+	if branch.State != shared.NotDeletable {
+		return ""
+	}
+
+	switch branch.Reason {
+	case shared.ProtectedBranch:
+		if branch.ProtectedBy != "" {
+			if branch.KeptByPolicy {
+				return "protected by " + branch.ProtectedBy + " in repo policy"
+			}
+			return "protected by " + branch.ProtectedBy
+		}
+		reason := "protected"
+		if !branch.ProtectedUntil.IsZero() {
+			reason += " until " + formatDate(branch.ProtectedUntil)
+		}
+		if branch.ProtectionNote != "" {
+			reason += ": " + formatProtection(branch.ProtectionNote, branch.ProtectionAuthor, branch.ProtectedAt)
+		}
+		return reason
+	case shared.DefaultBranch:
+		return "default branch"
+	case shared.MergeTarget:
+		if branch.KeptByPolicy {
+			return "merge target in repo policy"
 		}
+		return "merge target"
+	case shared.DetachedHead:
+		return "detached HEAD"
+	case shared.HasUncommittedChanges:
+		return "uncommitted changes"
+	case shared.NoPullRequest:
+		return "no PR found"
+	case shared.HasOpenPullRequest:
+		for _, pr := range branch.PullRequests {
+			if pr.State == shared.Open {
+				return fmt.Sprintf("open PR #%v", pr.Number)
+			}
+		}
+		return "open PR"
+	case shared.OnlyClosedPullRequests:
+		return "closed PR"
+	case shared.NotFullyMerged:
+		for _, pr := range branch.PullRequests {
+			if pr.State != shared.Merged {
+				continue
+			}
+			if n := branch.CommitsAheadOf(pr); n > 0 {
+				return fmt.Sprintf("ahead of merged PR by %d %s", n, pluralize(n, "commit"))
+			}
+		}
+		return "not fully merged"
+	case shared.Deselected:
+		return "deselected"
+	case shared.TipMoved:
+		return "tip moved since analysis"
+	case shared.CheckedOutInWorktree:
+		return "checked out in a worktree"
+	case shared.NoSwitchTarget:
+		return "current branch with no branch to switch to"
+	case shared.WithinGracePeriod:
+		if branch.KeptByPolicy {
+			return "within grace period of repo policy"
+		}
+		return "within grace period"
+	default:
+		return ""
+	}
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
 	}
+	return word + "s"
 }
 
 func getIssueNoColor(state shared.PullRequestState, isDraft bool) color.Attribute {
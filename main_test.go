@@ -2,19 +2,26 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/shared"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_DeletingBranchesWhenTheDryRunOptionIsFalse(t *testing.T) {
 	onlyCI(t)
 
-	results := captureOutput(func() { runMain(false, false) })
+	results := captureOutput(func() { runMain(runOptions{prune: true}) })
 
 	expected := fmt.Sprintf("%s %s", green("✔"), "Deleting branches...")
 	assert.Contains(t, results, expected)
@@ -23,25 +30,81 @@ func Test_DeletingBranchesWhenTheDryRunOptionIsFalse(t *testing.T) {
 func Test_DoNotDeleteBranchesWhenTheDryRunOptionIsTrue(t *testing.T) {
 	onlyCI(t)
 
-	results := captureOutput(func() { runMain(true, false) })
+	results := captureOutput(func() { runMain(runOptions{dryRun: true}) })
 
 	expected := fmt.Sprintf("%s %s", hiBlack("-"), "Deleting branches...")
 	assert.Contains(t, results, expected)
 }
 
+func Test_OutputJsonWhenTheJsonOptionIsTrue(t *testing.T) {
+	onlyCI(t)
+
+	results := captureOutput(func() { runMain(runOptions{dryRun: true, jsonOutput: true}) })
+
+	var report shared.Report
+	assert.Nil(t, json.Unmarshal([]byte(results), &report))
+	assert.Equal(t, shared.ReportSchemaVersion, report.SchemaVersion)
+	assert.True(t, report.DryRun)
+}
+
+func Test_CheckSummary(t *testing.T) {
+	assert.Equal(t, "No branches can be deleted", checkSummary(0))
+	assert.Equal(t, "1 branch can be deleted", checkSummary(1))
+	assert.Equal(t, "3 branches can be deleted", checkSummary(3))
+}
+
+func Test_GetReasonOfDeselectedBranches(t *testing.T) {
+	branch := shared.Branch{Name: "issue1", State: shared.NotDeletable, Reason: shared.Deselected}
+
+	assert.Equal(t, "deselected", getReason(branch))
+}
+
+func Test_GetReasonOfTheCurrentBranchWithNoBranchToSwitchTo(t *testing.T) {
+	branch := shared.Branch{Name: "issue1", State: shared.NotDeletable, Reason: shared.NoSwitchTarget}
+
+	assert.Equal(t, "current branch with no branch to switch to", getReason(branch))
+	assert.Equal(t, "noSwitchTarget", branch.Reason.String())
+}
+
 func Test_ProtectAndUnprotect(t *testing.T) {
 	onlyCI(t)
 
-	runProtect([]string{"main"}, false)
-	protectResults := captureOutput(func() { runMain(true, false) })
+	runProtect([]string{"main"}, []string{}, time.Time{}, "", false)
+	protectResults := captureOutput(func() { runMain(runOptions{dryRun: true}) })
 	expected := fmt.Sprintf("main %s", hiBlack("[protected]"))
 	assert.Contains(t, protectResults, expected)
 
-	runUnprotect([]string{"main"}, false)
-	unprotectResults := captureOutput(func() { runMain(true, false) })
+	runUnprotect([]string{"main"}, []string{}, false)
+	unprotectResults := captureOutput(func() { runMain(runOptions{dryRun: true}) })
 	assert.NotContains(t, unprotectResults, expected)
 }
 
+func Test_PromptBranches(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", State: shared.Deletable},
+		{Name: "issue2", State: shared.NotDeletable, Reason: shared.OnlyClosedPullRequests},
+		{Name: "main", State: shared.NotDeletable, Reason: shared.DefaultBranch},
+	}
+
+	var actual []string
+	var ok bool
+	captureOutput(func() { actual, ok = promptBranches(strings.NewReader("1 2\n\n"), branches) })
+
+	assert.True(t, ok)
+	assert.Equal(t, []string{"issue2"}, actual)
+}
+
+func Test_PromptBranchesWhenCanceled(t *testing.T) {
+	branches := []shared.Branch{
+		{Name: "issue1", State: shared.Deletable},
+	}
+
+	var ok bool
+	captureOutput(func() { _, ok = promptBranches(strings.NewReader("q\n"), branches) })
+
+	assert.False(t, ok)
+}
+
 func onlyCI(t *testing.T) {
 	if os.Getenv("CI") == "" {
 		t.Skip("skipping test in local")
@@ -63,3 +126,67 @@ func captureOutput(f func()) string {
 
 	return buf.String()
 }
+
+func Test_ApplySettings(t *testing.T) {
+	var dryRun bool
+	var remote string
+	flags := flag.NewFlagSet("poi", flag.ContinueOnError)
+	flags.BoolVar(&dryRun, "dry-run", false, "")
+	flags.StringVar(&remote, "remote", "", "")
+	flags.Parse([]string{"--remote", "origin"})
+
+	err := applySettings(flags, []cmd.Setting{
+		{Key: "gh-poi.dryRun", Flag: "dry-run", Value: "true", Source: "global"},
+		{Key: "gh-poi.remote", Flag: "remote", Value: "upstream", Source: "local"},
+	})
+
+	assert.Nil(t, err)
+	assert.True(t, dryRun)
+	assert.Equal(t, "origin", remote)
+}
+
+func Test_ApplySettingsReturnsAnErrorWhenTheValueIsInvalid(t *testing.T) {
+	var dryRun bool
+	flags := flag.NewFlagSet("poi", flag.ContinueOnError)
+	flags.BoolVar(&dryRun, "dry-run", false, "")
+	flags.Parse([]string{})
+
+	err := applySettings(flags, []cmd.Setting{
+		{Key: "gh-poi.dryRun", Flag: "dry-run", Value: "maybe", Source: "global"},
+	})
+
+	assert.NotNil(t, err)
+}
+
+func Test_ErrorExitCode(t *testing.T) {
+	ghErr := &shared.CommandError{Name: "gh", Args: []string{"api"}, Err: errors.New("exit status 1")}
+	gitErr := &shared.CommandError{Name: "git", Args: []string{"remote", "-v"}, Err: errors.New("exit status 1")}
+
+	assert.Equal(t, exitAPIError, errorExitCode(ghErr))
+	assert.Equal(t, exitError, errorExitCode(gitErr))
+	assert.Equal(t, exitError, errorExitCode(cmd.ErrNotFound))
+	assert.Equal(t, exitError, errorExitCode(&shared.CommandError{Name: "gh", Kind: shared.CommandNotFound, Err: errors.New("not found")}))
+	assert.Equal(t, exitError, errorExitCode(&shared.CommandError{Name: "git", Kind: shared.Unauthenticated, Err: errors.New("exit status 128")}))
+	assert.Equal(t, exitError, errorExitCode(&shared.CommandError{Name: "git", Kind: shared.RepoNotFound, Err: errors.New("exit status 128")}))
+}
+
+func Test_DescribeError(t *testing.T) {
+	err := &shared.CommandError{
+		Name: "gh",
+		Args: []string{"api", "--hostname", "ghe.example.com", "graphql"},
+		Kind: shared.Unauthenticated,
+		Err:  errors.New("exit status 4"),
+	}
+
+	message, hint := describeError(err)
+
+	assert.Equal(t, "not logged in to ghe.example.com", message)
+	assert.Equal(t, "Run `gh auth login --hostname ghe.example.com`", hint)
+}
+
+func Test_DescribeErrorReturnsTheErrorWhenTheCauseIsUnknown(t *testing.T) {
+	message, hint := describeError(cmd.ErrNotFound)
+
+	assert.Equal(t, "not found", message)
+	assert.Equal(t, "", hint)
+}
@@ -78,34 +78,64 @@ func (mr *MockConnectionMockRecorder) CheckoutBranch(ctx, branchName interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckoutBranch", reflect.TypeOf((*MockConnection)(nil).CheckoutBranch), ctx, branchName)
 }
 
-// DeleteBranches mocks base method.
-func (m *MockConnection) DeleteBranches(ctx context.Context, branchNames []string) (string, error) {
+// CreateBranch mocks base method.
+func (m *MockConnection) CreateBranch(ctx context.Context, branchName, oid string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteBranches", ctx, branchNames)
+	ret := m.ctrl.Call(m, "CreateBranch", ctx, branchName, oid)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DeleteBranches indicates an expected call of DeleteBranches.
-func (mr *MockConnectionMockRecorder) DeleteBranches(ctx, branchNames interface{}) *gomock.Call {
+// CreateBranch indicates an expected call of CreateBranch.
+func (mr *MockConnectionMockRecorder) CreateBranch(ctx, branchName, oid interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBranches", reflect.TypeOf((*MockConnection)(nil).DeleteBranches), ctx, branchNames)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBranch", reflect.TypeOf((*MockConnection)(nil).CreateBranch), ctx, branchName, oid)
 }
 
-// GetAssociatedRefNames mocks base method.
-func (m *MockConnection) GetAssociatedRefNames(ctx context.Context, oid string) (string, error) {
+// DeleteBranch mocks base method.
+func (m *MockConnection) DeleteBranch(ctx context.Context, branchName, oid string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAssociatedRefNames", ctx, oid)
+	ret := m.ctrl.Call(m, "DeleteBranch", ctx, branchName, oid)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAssociatedRefNames indicates an expected call of GetAssociatedRefNames.
-func (mr *MockConnectionMockRecorder) GetAssociatedRefNames(ctx, oid interface{}) *gomock.Call {
+// DeleteBranch indicates an expected call of DeleteBranch.
+func (mr *MockConnectionMockRecorder) DeleteBranch(ctx, branchName, oid interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAssociatedRefNames", reflect.TypeOf((*MockConnection)(nil).GetAssociatedRefNames), ctx, oid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBranch", reflect.TypeOf((*MockConnection)(nil).DeleteBranch), ctx, branchName, oid)
+}
+
+// DeleteRef mocks base method.
+func (m *MockConnection) DeleteRef(ctx context.Context, ref string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRef", ctx, ref)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRef indicates an expected call of DeleteRef.
+func (mr *MockConnectionMockRecorder) DeleteRef(ctx, ref interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRef", reflect.TypeOf((*MockConnection)(nil).DeleteRef), ctx, ref)
+}
+
+// GetBranchConfig mocks base method.
+func (m *MockConnection) GetBranchConfig(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBranchConfig", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranchConfig indicates an expected call of GetBranchConfig.
+func (mr *MockConnectionMockRecorder) GetBranchConfig(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchConfig", reflect.TypeOf((*MockConnection)(nil).GetBranchConfig), ctx)
 }
 
 // GetBranchNames mocks base method.
@@ -123,6 +153,36 @@ func (mr *MockConnectionMockRecorder) GetBranchNames(ctx interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchNames", reflect.TypeOf((*MockConnection)(nil).GetBranchNames), ctx)
 }
 
+// GetBranchRefs mocks base method.
+func (m *MockConnection) GetBranchRefs(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBranchRefs", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranchRefs indicates an expected call of GetBranchRefs.
+func (mr *MockConnectionMockRecorder) GetBranchRefs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchRefs", reflect.TypeOf((*MockConnection)(nil).GetBranchRefs), ctx)
+}
+
+// GetCommitGraph mocks base method.
+func (m *MockConnection) GetCommitGraph(ctx context.Context, oids, baseOids []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCommitGraph", ctx, oids, baseOids)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCommitGraph indicates an expected call of GetCommitGraph.
+func (mr *MockConnectionMockRecorder) GetCommitGraph(ctx, oids, baseOids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCommitGraph", reflect.TypeOf((*MockConnection)(nil).GetCommitGraph), ctx, oids, baseOids)
+}
+
 // GetConfig mocks base method.
 func (m *MockConnection) GetConfig(ctx context.Context, key string) (string, error) {
 	m.ctrl.T.Helper()
@@ -138,6 +198,36 @@ func (mr *MockConnectionMockRecorder) GetConfig(ctx, key interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfig", reflect.TypeOf((*MockConnection)(nil).GetConfig), ctx, key)
 }
 
+// GetConfigRegexp mocks base method.
+func (m *MockConnection) GetConfigRegexp(ctx context.Context, pattern string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfigRegexp", ctx, pattern)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfigRegexp indicates an expected call of GetConfigRegexp.
+func (mr *MockConnectionMockRecorder) GetConfigRegexp(ctx, pattern interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigRegexp", reflect.TypeOf((*MockConnection)(nil).GetConfigRegexp), ctx, pattern)
+}
+
+// GetConfigRegexpWithScope mocks base method.
+func (m *MockConnection) GetConfigRegexpWithScope(ctx context.Context, pattern string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfigRegexpWithScope", ctx, pattern)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfigRegexpWithScope indicates an expected call of GetConfigRegexpWithScope.
+func (mr *MockConnectionMockRecorder) GetConfigRegexpWithScope(ctx, pattern interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigRegexpWithScope", reflect.TypeOf((*MockConnection)(nil).GetConfigRegexpWithScope), ctx, pattern)
+}
+
 // GetLog mocks base method.
 func (m *MockConnection) GetLog(ctx context.Context, branchName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +258,21 @@ func (mr *MockConnectionMockRecorder) GetLsRemoteHeadOid(ctx, url, branchName in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLsRemoteHeadOid", reflect.TypeOf((*MockConnection)(nil).GetLsRemoteHeadOid), ctx, url, branchName)
 }
 
+// GetMergeBase mocks base method.
+func (m *MockConnection) GetMergeBase(ctx context.Context, oids []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMergeBase", ctx, oids)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMergeBase indicates an expected call of GetMergeBase.
+func (mr *MockConnectionMockRecorder) GetMergeBase(ctx, oids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMergeBase", reflect.TypeOf((*MockConnection)(nil).GetMergeBase), ctx, oids)
+}
+
 // GetMergedBranchNames mocks base method.
 func (m *MockConnection) GetMergedBranchNames(ctx context.Context, remoteName, branchName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -183,19 +288,64 @@ func (mr *MockConnectionMockRecorder) GetMergedBranchNames(ctx, remoteName, bran
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMergedBranchNames", reflect.TypeOf((*MockConnection)(nil).GetMergedBranchNames), ctx, remoteName, branchName)
 }
 
+// GetPullRequestCommits mocks base method.
+func (m *MockConnection) GetPullRequestCommits(ctx context.Context, hostname string, ids, cursors []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPullRequestCommits", ctx, hostname, ids, cursors)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPullRequestCommits indicates an expected call of GetPullRequestCommits.
+func (mr *MockConnectionMockRecorder) GetPullRequestCommits(ctx, hostname, ids, cursors interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPullRequestCommits", reflect.TypeOf((*MockConnection)(nil).GetPullRequestCommits), ctx, hostname, ids, cursors)
+}
+
 // GetPullRequests mocks base method.
-func (m *MockConnection) GetPullRequests(ctx context.Context, hostname, orgs, repos, queryHashes string) (string, error) {
+func (m *MockConnection) GetPullRequests(ctx context.Context, hostname, orgs, repos string, queryHashes, cursors []string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPullRequests", ctx, hostname, orgs, repos, queryHashes)
+	ret := m.ctrl.Call(m, "GetPullRequests", ctx, hostname, orgs, repos, queryHashes, cursors)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPullRequests indicates an expected call of GetPullRequests.
-func (mr *MockConnectionMockRecorder) GetPullRequests(ctx, hostname, orgs, repos, queryHashes interface{}) *gomock.Call {
+func (mr *MockConnectionMockRecorder) GetPullRequests(ctx, hostname, orgs, repos, queryHashes, cursors interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPullRequests", reflect.TypeOf((*MockConnection)(nil).GetPullRequests), ctx, hostname, orgs, repos, queryHashes, cursors)
+}
+
+// GetRefFile mocks base method.
+func (m *MockConnection) GetRefFile(ctx context.Context, ref, path string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefFile", ctx, ref, path)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefFile indicates an expected call of GetRefFile.
+func (mr *MockConnectionMockRecorder) GetRefFile(ctx, ref, path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefFile", reflect.TypeOf((*MockConnection)(nil).GetRefFile), ctx, ref, path)
+}
+
+// GetRefs mocks base method.
+func (m *MockConnection) GetRefs(ctx context.Context, prefix string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefs", ctx, prefix)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefs indicates an expected call of GetRefs.
+func (mr *MockConnectionMockRecorder) GetRefs(ctx, prefix interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPullRequests", reflect.TypeOf((*MockConnection)(nil).GetPullRequests), ctx, hostname, orgs, repos, queryHashes)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefs", reflect.TypeOf((*MockConnection)(nil).GetRefs), ctx, prefix)
 }
 
 // GetRemoteHeadOid mocks base method.
@@ -273,6 +423,36 @@ func (mr *MockConnectionMockRecorder) GetUncommittedChanges(ctx interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUncommittedChanges", reflect.TypeOf((*MockConnection)(nil).GetUncommittedChanges), ctx)
 }
 
+// GetWorkingTreeFile mocks base method.
+func (m *MockConnection) GetWorkingTreeFile(ctx context.Context, path string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkingTreeFile", ctx, path)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkingTreeFile indicates an expected call of GetWorkingTreeFile.
+func (mr *MockConnectionMockRecorder) GetWorkingTreeFile(ctx, path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkingTreeFile", reflect.TypeOf((*MockConnection)(nil).GetWorkingTreeFile), ctx, path)
+}
+
+// GetWorktrees mocks base method.
+func (m *MockConnection) GetWorktrees(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorktrees", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorktrees indicates an expected call of GetWorktrees.
+func (mr *MockConnectionMockRecorder) GetWorktrees(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorktrees", reflect.TypeOf((*MockConnection)(nil).GetWorktrees), ctx)
+}
+
 // RemoveConfig mocks base method.
 func (m *MockConnection) RemoveConfig(ctx context.Context, key string) (string, error) {
 	m.ctrl.T.Helper()
@@ -287,3 +467,48 @@ func (mr *MockConnectionMockRecorder) RemoveConfig(ctx, key interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveConfig", reflect.TypeOf((*MockConnection)(nil).RemoveConfig), ctx, key)
 }
+
+// RemoveConfigSection mocks base method.
+func (m *MockConnection) RemoveConfigSection(ctx context.Context, section string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveConfigSection", ctx, section)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveConfigSection indicates an expected call of RemoveConfigSection.
+func (mr *MockConnectionMockRecorder) RemoveConfigSection(ctx, section interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveConfigSection", reflect.TypeOf((*MockConnection)(nil).RemoveConfigSection), ctx, section)
+}
+
+// RemoveConfigValue mocks base method.
+func (m *MockConnection) RemoveConfigValue(ctx context.Context, key, value string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveConfigValue", ctx, key, value)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveConfigValue indicates an expected call of RemoveConfigValue.
+func (mr *MockConnectionMockRecorder) RemoveConfigValue(ctx, key, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveConfigValue", reflect.TypeOf((*MockConnection)(nil).RemoveConfigValue), ctx, key, value)
+}
+
+// UpdateRef mocks base method.
+func (m *MockConnection) UpdateRef(ctx context.Context, ref, oid string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRef", ctx, ref, oid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateRef indicates an expected call of UpdateRef.
+func (mr *MockConnectionMockRecorder) UpdateRef(ctx, ref, oid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRef", reflect.TypeOf((*MockConnection)(nil).UpdateRef), ctx, ref, oid)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/seachicken/gh-poi/cmd"
+	"github.com/seachicken/gh-poi/shared"
+)
+
+func isInteractive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// promptBranches lets the user toggle which branches to delete.
+// It returns false if the user canceled.
+func promptBranches(in io.Reader, branches []shared.Branch) ([]string, bool) {
+	candidates := []shared.Branch{}
+	selected := map[string]bool{}
+	for _, branch := range branches {
+		if cmd.IsSelectable(branch) {
+			candidates = append(candidates, branch)
+			selected[branch.Name] = branch.State == shared.Deletable
+		}
+	}
+	if len(candidates) == 0 {
+		return []string{}, true
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Println()
+		fmt.Fprintf(color.Output, "%s\n", whiteBold("Select branches to delete"))
+		for i, branch := range candidates {
+			check := "[ ]"
+			if selected[branch.Name] {
+				check = green("[x]")
+			}
+			fmt.Fprintf(color.Output, "  %s %s %s", hiBlack(fmt.Sprintf("%2d", i+1)), check, white(branch.Name))
+			if reason := getReason(branch); reason != "" {
+				fmt.Fprintf(color.Output, " %s", hiBlack("["+reason+"]"))
+			}
+			fmt.Fprintln(color.Output, "")
+			printPullRequests(branch, "         ")
+		}
+		fmt.Fprintf(color.Output, "%s\n",
+			hiBlack("Toggle by numbers (e.g. 1 3), a: all, n: none, q: quit, Enter: delete selected"))
+		fmt.Fprint(color.Output, "> ")
+
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, false
+		}
+
+		input := strings.TrimSpace(line)
+		switch input {
+		case "":
+			results := []string{}
+			for _, branch := range candidates {
+				if selected[branch.Name] {
+					results = append(results, branch.Name)
+				}
+			}
+			return results, true
+		case "q":
+			return nil, false
+		case "a", "n":
+			for _, branch := range candidates {
+				selected[branch.Name] = input == "a"
+			}
+		default:
+			for _, field := range strings.Fields(input) {
+				i, err := strconv.Atoi(field)
+				if err != nil || i < 1 || i > len(candidates) {
+					fmt.Fprintf(color.Output, "%s\n", red(fmt.Sprintf("invalid number: %s", field)))
+					continue
+				}
+				name := candidates[i-1].Name
+				selected[name] = !selected[name]
+			}
+		}
+	}
+}
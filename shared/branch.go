@@ -1,19 +1,37 @@
 package shared
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
 
 type (
 	BranchState int
 
+	BranchReason int
+
 	Branch struct {
-		Head          bool
-		Name          string
-		IsMerged      bool
-		IsProtected   bool
-		RemoteHeadOid string
-		Commits       []string
-		PullRequests  []PullRequest
-		State         BranchState
+		Head              bool
+		Name              string
+		Oid               string
+		IsMerged          bool
+		IsProtected       bool
+		ProtectedBy       string
+		ProtectedUntil    time.Time
+		ProtectionExpired bool
+		ProtectionNote    string
+		ProtectedAt       time.Time
+		ProtectionAuthor  string
+		KeptByPolicy      bool
+		DeleteError       string
+		DeleteHints       []string
+		Warnings          []string
+		RemoteHeadOid     string
+		Commits           []string
+		PullRequests      []PullRequest
+		State             BranchState
+		Reason            BranchReason
+		Trace             []string
 	}
 )
 
@@ -22,9 +40,103 @@ const (
 	NotDeletable
 	Deletable
 	Deleted
+	DeleteFailed
+)
+
+// The reason is set when the branch is not deletable.
+const (
+	NoReason BranchReason = iota
+	ProtectedBranch
+	DefaultBranch
+	DetachedHead
+	HasUncommittedChanges
+	NoPullRequest
+	HasOpenPullRequest
+	OnlyClosedPullRequests
+	NotFullyMerged
+	Deselected
+	MergeTarget
+	WithinGracePeriod
+	TipMoved
+	CheckedOutInWorktree
+	NoSwitchTarget
 )
 
 func (b Branch) IsDetached() bool {
 	detachedBranchNameRegex := regexp.MustCompile(`^\(.+\)`)
 	return detachedBranchNameRegex.MatchString(b.Name)
 }
+
+// QueryOid returns the commit used to search for the pull requests of the branch.
+func (b Branch) QueryOid() string {
+	if b.RemoteHeadOid != "" {
+		return b.RemoteHeadOid
+	}
+	if len(b.Commits) > 0 {
+		return b.Commits[len(b.Commits)-1]
+	}
+	return ""
+}
+
+// CommitsAheadOf returns the number of local commits on top of the pull
+// request, or -1 if the branch shares no commit with it.
+func (b Branch) CommitsAheadOf(pr PullRequest) int {
+	for i, oid := range b.Commits {
+		for _, prOid := range pr.Commits {
+			if oid == prOid {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (s BranchState) String() string {
+	switch s {
+	case NotDeletable:
+		return "notDeletable"
+	case Deletable:
+		return "deletable"
+	case Deleted:
+		return "deleted"
+	case DeleteFailed:
+		return "deleteFailed"
+	default:
+		return "unknown"
+	}
+}
+
+func (r BranchReason) String() string {
+	switch r {
+	case ProtectedBranch:
+		return "protected"
+	case DefaultBranch:
+		return "defaultBranch"
+	case DetachedHead:
+		return "detachedHead"
+	case HasUncommittedChanges:
+		return "uncommittedChanges"
+	case NoPullRequest:
+		return "noPullRequest"
+	case HasOpenPullRequest:
+		return "openPullRequest"
+	case OnlyClosedPullRequests:
+		return "closedPullRequest"
+	case NotFullyMerged:
+		return "notFullyMerged"
+	case Deselected:
+		return "deselected"
+	case MergeTarget:
+		return "mergeTarget"
+	case WithinGracePeriod:
+		return "withinGracePeriod"
+	case TipMoved:
+		return "tipMoved"
+	case CheckedOutInWorktree:
+		return "checkedOutInWorktree"
+	case NoSwitchTarget:
+		return "noSwitchTarget"
+	default:
+		return ""
+	}
+}
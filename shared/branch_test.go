@@ -0,0 +1,28 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommitsAheadOf(t *testing.T) {
+	branch := Branch{Name: "issue1",
+		Commits: []string{
+			"b8a2645298053fb62ea03e27feea6c483d3fd27e",
+			"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+		},
+	}
+
+	t.Run("ahead", func(t *testing.T) {
+		assert.Equal(t, 1, branch.CommitsAheadOf(PullRequest{
+			Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+		}))
+	})
+
+	t.Run("not shared", func(t *testing.T) {
+		assert.Equal(t, -1, branch.CommitsAheadOf(PullRequest{
+			Commits: []string{"6ebe3d30d23531af56bd23b5a098d3ccae2a534a"},
+		}))
+	})
+}
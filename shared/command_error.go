@@ -0,0 +1,108 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+type (
+	// CommandError is returned by the Connection when an external command fails.
+	CommandError struct {
+		Name   string
+		Args   []string
+		Stderr string
+		Kind   CommandErrorKind
+		Err    error
+	}
+
+	CommandErrorKind int
+)
+
+const (
+	UnknownError CommandErrorKind = iota
+	CommandNotFound
+	Unauthenticated
+	RepoNotFound
+	RateLimited
+	NetworkError
+)
+
+func (e *CommandError) Error() string {
+	message := fmt.Sprintf("failed to run external command: %s, args: %v\n %v", e.Name, e.Args, e.Err)
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		message += "\n " + stderr
+	}
+	return message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit status of the command, or -1 when it did not exit.
+func (e *CommandError) ExitCode() int {
+	var exitErr *exec.ExitError
+	if errors.As(e.Err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// Message returns the first error line printed by the command, like
+// "cannot lock ref 'refs/heads/issue1': is at ... but expected ...".
+func (e *CommandError) Message() string {
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"error: ", "fatal: "} {
+			line = strings.TrimPrefix(line, prefix)
+		}
+		if line != "" && !strings.HasPrefix(line, "hint:") {
+			return line
+		}
+	}
+	return e.Err.Error()
+}
+
+// Hints returns the hint lines printed by git, like "Use 'git worktree list' to ...".
+func (e *CommandError) Hints() []string {
+	results := []string{}
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "hint:") {
+			continue
+		}
+		if hint := strings.TrimSpace(strings.TrimPrefix(line, "hint:")); hint != "" {
+			results = append(results, hint)
+		}
+	}
+	return results
+}
+
+// Hostname returns the value of the --hostname argument given to gh.
+func (e *CommandError) Hostname() string {
+	for i, arg := range e.Args {
+		if arg == "--hostname" && i+1 < len(e.Args) {
+			return e.Args[i+1]
+		}
+	}
+	return ""
+}
+
+func (k CommandErrorKind) String() string {
+	switch k {
+	case CommandNotFound:
+		return "commandNotFound"
+	case Unauthenticated:
+		return "unauthenticated"
+	case RepoNotFound:
+		return "repoNotFound"
+	case RateLimited:
+		return "rateLimited"
+	case NetworkError:
+		return "networkError"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,42 @@
+package shared
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CommandErrorMessageAndHints(t *testing.T) {
+	err := &CommandError{
+		Name: "git",
+		Args: []string{"checkout", "--quiet", "issue1"},
+		Stderr: "hint: If you meant to check out a remote tracking branch on, e.g. 'origin',\n" +
+			"hint: you can do so by fully qualifying the name with the --track option:\n" +
+			"hint:\n" +
+			"fatal: 'issue1' matched multiple (2) remote tracking branches\n",
+		Err: errors.New("exit status 128"),
+	}
+
+	assert.Equal(t, "'issue1' matched multiple (2) remote tracking branches", err.Message())
+	assert.Equal(t, []string{
+		"If you meant to check out a remote tracking branch on, e.g. 'origin',",
+		"you can do so by fully qualifying the name with the --track option:",
+	}, err.Hints())
+}
+
+func Test_CommandErrorMessageWithoutStderr(t *testing.T) {
+	err := &CommandError{Name: "git", Err: errors.New("exit status 1")}
+
+	assert.Equal(t, "exit status 1", err.Message())
+	assert.Equal(t, []string{}, err.Hints())
+}
+
+func Test_CommandErrorExitCode(t *testing.T) {
+	err := &CommandError{Name: "sh", Args: []string{"-c", "exit 129"}, Err: exec.Command("sh", "-c", "exit 129").Run()}
+	assert.Equal(t, 129, err.ExitCode())
+
+	err = &CommandError{Name: "gh", Args: []string{"api"}, Err: errors.New("signal: killed")}
+	assert.Equal(t, -1, err.ExitCode())
+}
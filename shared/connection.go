@@ -13,12 +13,27 @@ type Connection interface {
 	GetRemoteHeadOid(ctx context.Context, remoteName string, branchName string) (string, error)
 	GetLsRemoteHeadOid(ctx context.Context, url string, branchName string) (string, error)
 	GetLog(ctx context.Context, branchName string) (string, error)
-	GetAssociatedRefNames(ctx context.Context, oid string) (string, error)
-	GetPullRequests(ctx context.Context, hostname string, orgs string, repos string, queryHashes string) (string, error)
+	GetBranchRefs(ctx context.Context) (string, error)
+	GetMergeBase(ctx context.Context, oids []string) (string, error)
+	GetCommitGraph(ctx context.Context, oids []string, baseOids []string) (string, error)
+	GetPullRequests(ctx context.Context, hostname string, orgs string, repos string, queryHashes []string, cursors []string) (string, error)
+	GetPullRequestCommits(ctx context.Context, hostname string, ids []string, cursors []string) (string, error)
 	GetUncommittedChanges(ctx context.Context) (string, error)
+	GetWorkingTreeFile(ctx context.Context, path string) (string, error)
+	GetRefFile(ctx context.Context, ref string, path string) (string, error)
 	GetConfig(ctx context.Context, key string) (string, error)
+	GetConfigRegexp(ctx context.Context, pattern string) (string, error)
+	GetConfigRegexpWithScope(ctx context.Context, pattern string) (string, error)
+	GetBranchConfig(ctx context.Context) (string, error)
 	AddConfig(ctx context.Context, key string, value string) (string, error)
 	RemoveConfig(ctx context.Context, key string) (string, error)
+	RemoveConfigValue(ctx context.Context, key string, value string) (string, error)
+	RemoveConfigSection(ctx context.Context, section string) (string, error)
+	GetRefs(ctx context.Context, prefix string) (string, error)
+	UpdateRef(ctx context.Context, ref string, oid string) (string, error)
+	DeleteRef(ctx context.Context, ref string) (string, error)
 	CheckoutBranch(ctx context.Context, branchName string) (string, error)
-	DeleteBranches(ctx context.Context, branchNames []string) (string, error)
+	CreateBranch(ctx context.Context, branchName string, oid string) (string, error)
+	DeleteBranch(ctx context.Context, branchName string, oid string) (string, error)
+	GetWorktrees(ctx context.Context) (string, error)
 }
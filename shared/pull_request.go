@@ -1,16 +1,24 @@
 package shared
 
+import "time"
+
 type (
 	PullRequestState int
 
 	PullRequest struct {
-		Name    string
-		State   PullRequestState
-		IsDraft bool
-		Number  int
-		Commits []string
-		Url     string
-		Author  string
+		Name     string
+		State    PullRequestState
+		IsDraft  bool
+		Number   int
+		Commits  []string
+		Url      string
+		Author   string
+		ClosedAt time.Time
+		ID       string
+		// CommitCount can be more than the number of Commits, which are the last ones.
+		CommitCount int
+		// CommitsCursor points to the oldest of Commits while the older ones are not fetched.
+		CommitsCursor string
 	}
 )
 
@@ -19,3 +27,16 @@ const (
 	Merged
 	Open
 )
+
+func (s PullRequestState) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Merged:
+		return "merged"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
@@ -26,7 +26,8 @@ func GetQueryHashes(branches []Branch) []string {
 
 	var hashes strings.Builder
 	for i, branch := range branches {
-		if branch.RemoteHeadOid == "" && len(branch.Commits) == 0 {
+		oid := branch.QueryOid()
+		if oid == "" {
 			continue
 		}
 
@@ -34,12 +35,6 @@ func GetQueryHashes(branches []Branch) []string {
 		if i == len(branches)-1 {
 			separator = ""
 		}
-		oid := ""
-		if branch.RemoteHeadOid == "" {
-			oid = branch.Commits[len(branch.Commits)-1]
-		} else {
-			oid = branch.RemoteHeadOid
-		}
 		hash := fmt.Sprintf("hash:%s%s", oid, separator)
 
 		// https://docs.github.com/en/rest/reference/search#limitations-on-query-length
@@ -56,3 +51,31 @@ func GetQueryHashes(branches []Branch) []string {
 
 	return results
 }
+
+// MaxSearchesPerQuery is the number of searches combined into a single GraphQL query.
+// Each search can return 100 pull requests with 10 commits, so 20 searches stay far below
+// the limit of 500,000 nodes per query.
+const MaxSearchesPerQuery = 20
+
+// GetQueryBatches groups the query hashes into the searches of each GraphQL query.
+func GetQueryBatches(queryHashes []string) [][]string {
+	results := [][]string{}
+	for start := 0; start < len(queryHashes); start += MaxSearchesPerQuery {
+		end := start + MaxSearchesPerQuery
+		if end > len(queryHashes) {
+			end = len(queryHashes)
+		}
+		results = append(results, queryHashes[start:end])
+	}
+	return results
+}
+
+// GetSearchAlias returns the alias of the i-th search in a GraphQL query.
+func GetSearchAlias(i int) string {
+	return fmt.Sprintf("search%d", i)
+}
+
+// GetCommitsAlias returns the alias of the commits of the i-th pull request in a GraphQL query.
+func GetCommitsAlias(i int) string {
+	return fmt.Sprintf("commits%d", i)
+}
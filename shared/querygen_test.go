@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,6 +21,21 @@ func Test_GetQueryRepos(t *testing.T) {
 	)
 }
 
+func Test_GetQueryBatches(t *testing.T) {
+	queryHashes := []string{}
+	for i := 0; i < 45; i++ {
+		queryHashes = append(queryHashes, fmt.Sprintf("hash:%d", i))
+	}
+
+	actual := GetQueryBatches(queryHashes)
+
+	assert.Equal(t, 3, len(actual))
+	assert.Equal(t, 20, len(actual[0]))
+	assert.Equal(t, "hash:20", actual[1][0])
+	assert.Equal(t, []string{"hash:40", "hash:41", "hash:42", "hash:43", "hash:44"}, actual[2])
+	assert.Equal(t, [][]string{}, GetQueryBatches([]string{}))
+}
+
 func Test_GetQueryHashesWithCommitOid(t *testing.T) {
 	assert.Equal(t,
 		[]string{
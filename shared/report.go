@@ -0,0 +1,145 @@
+package shared
+
+import (
+	"errors"
+	"time"
+)
+
+// ReportSchemaVersion is incremented whenever a field of the JSON report
+// is renamed or removed. Adding fields does not change the version.
+const ReportSchemaVersion = 1
+
+type (
+	Report struct {
+		SchemaVersion int            `json:"schemaVersion"`
+		DryRun        bool           `json:"dryRun"`
+		Branches      []BranchReport `json:"branches"`
+	}
+
+	BranchReport struct {
+		Name              string              `json:"name"`
+		Head              bool                `json:"head"`
+		State             string              `json:"state"`
+		Reason            string              `json:"reason"`
+		IsMerged          bool                `json:"isMerged"`
+		IsProtected       bool                `json:"isProtected"`
+		ProtectedBy       string              `json:"protectedBy"`
+		ProtectedUntil    string              `json:"protectedUntil"`
+		ProtectionExpired bool                `json:"protectionExpired"`
+		ProtectionNote    string              `json:"protectionNote"`
+		ProtectedAt       string              `json:"protectedAt"`
+		ProtectionAuthor  string              `json:"protectionAuthor"`
+		KeptByPolicy      bool                `json:"keptByPolicy"`
+		DeleteError       string              `json:"deleteError"`
+		DeleteHints       []string            `json:"deleteHints"`
+		Warnings          []string            `json:"warnings"`
+		RemoteHeadOid     string              `json:"remoteHeadOid"`
+		Commits           []string            `json:"commits"`
+		PullRequests      []PullRequestReport `json:"pullRequests"`
+	}
+
+	// ErrorReport is output instead of the Report when poi fails.
+	ErrorReport struct {
+		SchemaVersion int         `json:"schemaVersion"`
+		Error         ErrorDetail `json:"error"`
+	}
+
+	ErrorDetail struct {
+		Kind    string `json:"kind"`
+		Command string `json:"command"`
+		Message string `json:"message"`
+		Hint    string `json:"hint"`
+	}
+
+	PullRequestReport struct {
+		Number      int      `json:"number"`
+		HeadRefName string   `json:"headRefName"`
+		State       string   `json:"state"`
+		IsDraft     bool     `json:"isDraft"`
+		Url         string   `json:"url"`
+		Author      string   `json:"author"`
+		Commits     []string `json:"commits"`
+	}
+)
+
+func NewReport(branches []Branch, dryRun bool) Report {
+	results := []BranchReport{}
+	for _, branch := range branches {
+		results = append(results, toBranchReport(branch))
+	}
+
+	return Report{
+		SchemaVersion: ReportSchemaVersion,
+		DryRun:        dryRun,
+		Branches:      results,
+	}
+}
+
+func NewErrorReport(err error, message string, hint string) ErrorReport {
+	detail := ErrorDetail{
+		Kind:    UnknownError.String(),
+		Message: message,
+		Hint:    hint,
+	}
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		detail.Kind = commandErr.Kind.String()
+		detail.Command = commandErr.Name
+	}
+
+	return ErrorReport{
+		SchemaVersion: ReportSchemaVersion,
+		Error:         detail,
+	}
+}
+
+func toBranchReport(branch Branch) BranchReport {
+	prs := []PullRequestReport{}
+	for _, pr := range branch.PullRequests {
+		prs = append(prs, PullRequestReport{
+			Number:      pr.Number,
+			HeadRefName: pr.Name,
+			State:       pr.State.String(),
+			IsDraft:     pr.IsDraft,
+			Url:         pr.Url,
+			Author:      pr.Author,
+			Commits:     nonNil(pr.Commits),
+		})
+	}
+
+	return BranchReport{
+		Name:              branch.Name,
+		Head:              branch.Head,
+		State:             branch.State.String(),
+		Reason:            branch.Reason.String(),
+		IsMerged:          branch.IsMerged,
+		IsProtected:       branch.IsProtected,
+		ProtectedBy:       branch.ProtectedBy,
+		ProtectedUntil:    formatTime(branch.ProtectedUntil),
+		ProtectionExpired: branch.ProtectionExpired,
+		ProtectionNote:    branch.ProtectionNote,
+		ProtectedAt:       formatTime(branch.ProtectedAt),
+		ProtectionAuthor:  branch.ProtectionAuthor,
+		KeptByPolicy:      branch.KeptByPolicy,
+		DeleteError:       branch.DeleteError,
+		DeleteHints:       nonNil(branch.DeleteHints),
+		Warnings:          nonNil(branch.Warnings),
+		RemoteHeadOid:     branch.RemoteHeadOid,
+		Commits:           nonNil(branch.Commits),
+		PullRequests:      prs,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
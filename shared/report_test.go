@@ -0,0 +1,109 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewReport(t *testing.T) {
+	report := NewReport([]Branch{
+		{Head: false, Name: "issue1", IsMerged: false, IsProtected: false,
+			RemoteHeadOid: "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+			Commits: []string{
+				"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+			},
+			PullRequests: []PullRequest{
+				{Name: "issue1", State: Merged, IsDraft: false, Number: 1,
+					Commits: []string{"a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"},
+					Url:     "https://github.com/owner/repo/pull/1", Author: "owner",
+				},
+			},
+			State: Deletable,
+		},
+		{Head: true, Name: "main", IsMerged: true, IsProtected: true,
+			RemoteHeadOid: "",
+			Commits:       nil,
+			PullRequests:  []PullRequest{}, State: NotDeletable, Reason: DefaultBranch,
+		},
+	}, true)
+
+	actual, _ := json.Marshal(report)
+
+	assert.JSONEq(t, `{
+  "schemaVersion": 1,
+  "dryRun": true,
+  "branches": [
+    {
+      "name": "issue1",
+      "head": false,
+      "state": "deletable",
+      "reason": "",
+      "isMerged": false,
+      "isProtected": false,
+      "protectedBy": "",
+      "protectedUntil": "",
+      "protectionExpired": false,
+      "protectionNote": "",
+      "protectedAt": "",
+      "protectionAuthor": "",
+      "keptByPolicy": false,
+      "deleteError": "",
+      "deleteHints": [],
+      "warnings": [],
+      "remoteHeadOid": "a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0",
+      "commits": ["a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"],
+      "pullRequests": [
+        {
+          "number": 1,
+          "headRefName": "issue1",
+          "state": "merged",
+          "isDraft": false,
+          "url": "https://github.com/owner/repo/pull/1",
+          "author": "owner",
+          "commits": ["a97e9630426df5d34ca9ee77ae1159bdfd5ff8f0"]
+        }
+      ]
+    },
+    {
+      "name": "main",
+      "head": true,
+      "state": "notDeletable",
+      "reason": "defaultBranch",
+      "isMerged": true,
+      "isProtected": true,
+      "protectedBy": "",
+      "protectedUntil": "",
+      "protectionExpired": false,
+      "protectionNote": "",
+      "protectedAt": "",
+      "protectionAuthor": "",
+      "keptByPolicy": false,
+      "deleteError": "",
+      "deleteHints": [],
+      "warnings": [],
+      "remoteHeadOid": "",
+      "commits": [],
+      "pullRequests": []
+    }
+  ]
+}`, string(actual))
+}
+
+func Test_NewErrorReport(t *testing.T) {
+	err := &CommandError{Name: "gh", Kind: RateLimited, Err: errors.New("exit status 1")}
+
+	actual, _ := json.Marshal(NewErrorReport(err, "API rate limit exceeded on github.com", "Wait a few minutes and try again"))
+
+	assert.JSONEq(t, `{
+  "schemaVersion": 1,
+  "error": {
+    "kind": "rateLimited",
+    "command": "gh",
+    "message": "API rate limit exceeded on github.com",
+    "hint": "Wait a few minutes and try again"
+  }
+}`, string(actual))
+}